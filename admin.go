@@ -0,0 +1,127 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// writeJSON encodes v as the JSON response body
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write JSON response: %v", err)
+	}
+}
+
+// jsonError is the body of every error of the JSON endpoints
+type jsonError struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"` // the HTTP status
+}
+
+// writeError replies to a JSON endpoint with msg and status, in the manner
+// of http.Error
+func writeError(w http.ResponseWriter, msg string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(jsonError{Error: msg, Code: status}); err != nil {
+		log.Printf("Failed to write JSON error: %v", err)
+	}
+}
+
+// queryIDs runs a query selecting a single id column
+func queryIDs(db *sql.DB, query string, args ...any) ([]int, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// queueRefetch marks games to be re-ingested on the next update
+func queueRefetch(db *sql.DB, ids []int) error {
+	for _, id := range ids {
+		if _, err := db.Exec("insert into refetch (game_id) values (?) on conflict (game_id) do nothing;", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// takeRefetched removes the given games from the refetch queue once they have
+// been re-ingested
+func takeRefetched(db *sql.DB, seen map[int]bool) {
+	queued, err := queryIDs(db, "select game_id from refetch;")
+	if err != nil {
+		log.Printf("Failed to read refetch queue: %v", err)
+		return
+	}
+
+	for _, id := range queued {
+		if !seen[id] {
+			continue
+		}
+		if _, err := db.Exec("delete from refetch where game_id = ?;", id); err != nil {
+			log.Printf("Failed to dequeue game %d: %v", id, err)
+		}
+	}
+}
+
+// Serve games lacking a cover or previews, queueing them for refetch when
+// fix is set
+func serveIncomplete(db *sql.DB, fix bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		noCover, err := queryIDs(db, `
+			select g.id from game g
+			where not exists (select 1 from cover c where c.game_id = g.id)
+			order by g.id;
+		`)
+		if err != nil {
+			writeError(w, "Error querying covers", http.StatusInternalServerError)
+			return
+		}
+
+		noPreview, err := queryIDs(db, `
+			select g.id from game g
+			where not exists (select 1 from preview p where p.game_id = g.id)
+			order by g.id;
+		`)
+		if err != nil {
+			writeError(w, "Error querying previews", http.StatusInternalServerError)
+			return
+		}
+
+		if fix {
+			if err := queueRefetch(db, append(noCover, noPreview...)); err != nil {
+				writeError(w, "Error queueing games", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		queued, err := queryIDs(db, "select game_id from refetch order by game_id;")
+		if err != nil {
+			writeError(w, "Error reading refetch queue", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, map[string][]int{
+			"missing_cover":    noCover,
+			"missing_previews": noPreview,
+			"queued":           queued,
+		})
+	}
+}
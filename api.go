@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	APITIMEOUT  time.Duration // timeout of API and metadata requests
+	APIMAXBYTES int           // largest API response accepted, 0 for no limit
+	APIFILE     string        // saved API response read instead of the live API
+	APIROWS     int           // games per API request, 0 for the API's default
+
+	apiClient *http.Client
+)
+
+// apiMaxRows is the largest F95_RSS_API_ROWS accepted
+const apiMaxRows = 100
+
+// validateAPIURL checks that the configured endpoint is a usable http(s) URL
+func validateAPIURL() error {
+	u, err := url.Parse(BASE_API)
+	if err != nil {
+		return fmt.Errorf("F95_RSS_API_URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("F95_RSS_API_URL: unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("F95_RSS_API_URL: missing host")
+	}
+	if APIROWS < 0 || APIROWS > apiMaxRows {
+		return fmt.Errorf("F95_RSS_API_ROWS: %d is not between 0 and %d", APIROWS, apiMaxRows)
+	}
+
+	_, err = apiURL()
+	return err
+}
+
+// apiURL composes the list endpoint from BASE_API, the rows count and the
+// configured extra query parameters, which take precedence over the defaults
+func apiURL() (string, error) {
+	u, err := url.Parse(BASE_API)
+	if err != nil {
+		return "", fmt.Errorf("invalid API URL: %w", err)
+	}
+
+	q := u.Query()
+	if APIROWS > 0 {
+		q.Set("rows", strconv.Itoa(APIROWS))
+	}
+	extra, err := url.ParseQuery(APIQUERY)
+	if err != nil {
+		return "", fmt.Errorf("invalid F95_RSS_API_QUERY: %w", err)
+	}
+	for k, v := range extra {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// readDataFile decodes a saved API response, for working offline
+func readDataFile(path string) (F95, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return F95{}, fmt.Errorf("failed to read API file: %w", err)
+	}
+	defer file.Close()
+
+	data, err := decodeData(file)
+	if err == errTruncated {
+		return data, nil
+	}
+	return data, err
+}
+
+// errTruncated stops decoding once the body is broken past a usable prefix.
+// decodeData returns it along with the entries read before the break.
+var errTruncated = errors.New("API response truncated")
+
+// decodeData decodes an API response entry by entry so that a single
+// malformed record, or a body truncated half-way through, only loses the
+// affected entries instead of the whole update.
+func decodeData(r io.Reader) (data F95, err error) {
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return data, err
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return data, fmt.Errorf("failed to read API response: %w", err)
+		}
+
+		switch key {
+		case "status":
+			if err := dec.Decode(&data.Status); err != nil {
+				return data, fmt.Errorf("failed to read API status: %w", err)
+			}
+		case "msg":
+			return data, decodeMsg(dec, &data)
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return data, fmt.Errorf("failed to read API response: %w", err)
+			}
+		}
+	}
+
+	return data, nil
+}
+
+func decodeMsg(dec *json.Decoder, data *F95) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("failed to read API message: %w", err)
+	}
+	if tok != json.Delim('{') {
+		// The API reports errors as {"status":"error","msg":"..."}
+		return fmt.Errorf("API returned %s: %v", data.Status, tok)
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("failed to read API message: %w", err)
+		}
+
+		if key != "data" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return fmt.Errorf("failed to read API message: %w", err)
+			}
+			continue
+		}
+
+		if err := decodeEntries(dec, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func decodeEntries(dec *json.Decoder, data *F95) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+
+	skipped := 0
+	defer func() {
+		if skipped > 0 {
+			log.Printf("Skipped %d malformed API entries", skipped)
+		}
+	}()
+
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			// The body is truncated or broken beyond this point; keep what we
+			// have unless there is nothing to keep.
+			if len(data.Msg.Data) == 0 {
+				return fmt.Errorf("failed to decode API data: %w", err)
+			}
+			log.Printf("API response cut short after %d entries: %v", len(data.Msg.Data), err)
+			return errTruncated
+		}
+
+		var f F95DATA
+		if err := json.Unmarshal(raw, &f); err != nil {
+			skipped++
+			continue
+		}
+		if f.ThreadID <= 0 || f.Title == "" {
+			skipped++
+			continue
+		}
+
+		data.Msg.Data = append(data.Msg.Data, f)
+	}
+
+	return nil
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("failed to read API response: %w", err)
+	}
+	if tok != want {
+		return fmt.Errorf("unexpected API response: expected %v, got %v", want, tok)
+	}
+	return nil
+}
+
+// sanitizeText drops invalid UTF-8 and characters that XML 1.0 can't carry,
+// such as most control characters, from API supplied text
+func sanitizeText(s string) string {
+	s = strings.ToValidUTF8(s, "")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return r
+		case r < 0x20, r >= 0xD800 && r <= 0xDFFF, r == 0xFFFE, r == 0xFFFF:
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", "Game", "Game"},
+		{"trimmed", "  Game \n", "Game"},
+		{"control characters", "Ga\x00m\x1be\x7f", "Game\x7f"},
+		{"whitespace kept", "a\tb\nc\rd", "a\tb\nc\rd"},
+		{"invalid utf-8", "Ga\xffme", "Game"},
+		{"noncharacters", "Game￾￿", "Game"},
+		{"markup untouched", `<b>"Game" & co</b>`, `<b>"Game" & co</b>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeText(tt.in); got != tt.want {
+				t.Errorf("sanitizeText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeedEscaping(t *testing.T) {
+	titles := []string{
+		`Game "quoted"`,
+		`Game <One>`,
+		`Fish & Chips`,
+		`It's </title><script>alert(1)</script>`,
+		`]]> in a title`,
+	}
+
+	for _, title := range titles {
+		item := &Item{
+			Title:    title,
+			Link:     "https://f95zone.to/threads/1",
+			Name:     title,
+			Overview: title,
+			Cover:    `https://attachments.f95zone.to/a"b.jpg`,
+		}
+		item.Description = describe(item)
+		if strings.Contains(item.Description, "<One>") || strings.Contains(item.Description, "<script>") || strings.Contains(item.Description, `a"b`) {
+			t.Errorf("unescaped markup in description %s", item.Description)
+		}
+		feed := newFeed(&Channel{Title: "Test", Link: "https://f95zone.to/latest"}, []*Item{item})
+
+		for _, format := range []string{"rss", "atom", "json"} {
+			t.Run(format+"/"+title, func(t *testing.T) {
+				body, _, err := marshalFeed(feed, format, "http://localhost/feed")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if strings.Contains(string(body), "<One>") || strings.Contains(string(body), "<script>") {
+					t.Errorf("unescaped markup in %s", body)
+				}
+
+				var got string
+				switch format {
+				case "rss":
+					var rss struct {
+						Items []struct {
+							Title string `xml:"title"`
+						} `xml:"channel>item"`
+					}
+					err = xml.Unmarshal(body, &rss)
+					if err == nil && len(rss.Items) == 1 {
+						got = rss.Items[0].Title
+					}
+				case "atom":
+					var atom struct {
+						Entries []struct {
+							Title string `xml:"title"`
+						} `xml:"entry"`
+					}
+					err = xml.Unmarshal(body, &atom)
+					if err == nil && len(atom.Entries) == 1 {
+						got = atom.Entries[0].Title
+					}
+				case "json":
+					var feed struct {
+						Items []struct {
+							Title string `json:"title"`
+						} `json:"items"`
+					}
+					err = json.Unmarshal(body, &feed)
+					if err == nil && len(feed.Items) == 1 {
+						got = feed.Items[0].Title
+					}
+				}
+				if err != nil {
+					t.Fatalf("output doesn't parse: %v\n%s", err, body)
+				}
+				if got != title {
+					t.Errorf("title read back as %q, want %q", got, title)
+				}
+			})
+		}
+	}
+}
+
+// TestGetDataTruncated checks a truncated response is ingested without
+// keeping its validators, so the next fetch gets the full list again
+func TestGetDataTruncated(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	full := `{"status":"ok","msg":{"data":[{"thread_id":1,"title":"A"},{"thread_id":2,"title":"B"}]}}`
+	body := full[:strings.Index(full, `{"thread_id":2`)+5]
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+
+	db := newTestDB(t)
+	oldAPI, oldFile := BASE_API, APIFILE
+	BASE_API, APIFILE = srv.URL, ""
+	defer func() { BASE_API, APIFILE = oldAPI, oldFile }()
+
+	data, err := getData(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Msg.Data) != 1 {
+		t.Errorf("got %d entries, want 1", len(data.Msg.Data))
+	}
+	if etag := getMeta(db, "api_etag"); etag != "" {
+		t.Errorf("api_etag = %q after a truncated response, want none", etag)
+	}
+
+	body = full
+	if _, err := getData(db); err != nil {
+		t.Fatal(err)
+	}
+	if etag := getMeta(db, "api_etag"); etag != `"v1"` {
+		t.Errorf("api_etag = %q after a full response, want \"v1\"", etag)
+	}
+}
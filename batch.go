@@ -0,0 +1,46 @@
+package main
+
+import "strings"
+
+// sqliteMaxVars is the lowest bound parameter limit of SQLite builds
+const sqliteMaxVars = 999
+
+// insertRows runs insert, a statement ending in "values", for all rows at
+// once, chunked to stay under the parameter limit. Every row must have the
+// same number of columns.
+func insertRows(q queryer, insert string, rows [][]any) error {
+	return insertChunks(q, insert, "", rows)
+}
+
+// insertRowsIgnore is insertRows skipping the rows that conflict with
+// existing ones
+func insertRowsIgnore(q queryer, insert string, rows [][]any) error {
+	return insertChunks(q, insert, " on conflict do nothing", rows)
+}
+
+// insertChunks runs insert, the rows of a chunk and then suffix
+func insertChunks(q queryer, insert, suffix string, rows [][]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	cols := len(rows[0])
+	placeholder := "(?" + strings.Repeat(", ?", cols-1) + ")"
+	chunk := sqliteMaxVars / cols
+
+	for start := 0; start < len(rows); start += chunk {
+		end := min(start+chunk, len(rows))
+
+		values := make([]string, 0, end-start)
+		args := make([]any, 0, (end-start)*cols)
+		for _, row := range rows[start:end] {
+			values = append(values, placeholder)
+			args = append(args, row...)
+		}
+
+		if _, err := q.Exec(insert+" "+strings.Join(values, ", ")+suffix+";", args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import "testing"
+
+func TestInsertRows(t *testing.T) {
+	db := newTestDB(t)
+
+	// More rows than fit in one statement of two columns
+	var rows [][]any
+	for i := 0; i < sqliteMaxVars; i++ {
+		rows = append(rows, []any{1, i})
+	}
+	if err := insertRows(db, "insert into tags (game_id, tag_id) values", rows); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	if err := db.QueryRow("select count(*) from tags;").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != len(rows) {
+		t.Fatalf("inserted %d rows, want %d", n, len(rows))
+	}
+
+	if err := insertRows(db, "insert into tags (game_id, tag_id) values", nil); err != nil {
+		t.Fatalf("no rows: %v", err)
+	}
+}
+
+func TestInsertRowsIgnore(t *testing.T) {
+	db := newTestDB(t)
+
+	rows := [][]any{{1, 1}, {1, 2}}
+	for range 2 {
+		if err := insertRowsIgnore(db, "insert into tags (game_id, tag_id) values", rows); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var n int
+	if err := db.QueryRow("select count(*) from tags;").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != len(rows) {
+		t.Fatalf("%d rows after inserting twice, want %d", n, len(rows))
+	}
+}
+
+// BenchmarkInsertRows inserts the tags of 10 games, 30 each, in chunked
+// statements and one statement per row
+func BenchmarkInsertRows(b *testing.B) {
+	db := newTestDB(b)
+
+	var rows [][]any
+	for game := 1; game <= 10; game++ {
+		for tag := 0; tag < 30; tag++ {
+			rows = append(rows, []any{game, tag})
+		}
+	}
+
+	inserts := []struct {
+		name   string
+		insert func() error
+	}{
+		{"chunked", func() error {
+			return insertRows(db, "insert or ignore into tags (game_id, tag_id) values", rows)
+		}},
+		{"per row", func() error {
+			for _, row := range rows {
+				if _, err := db.Exec("insert or ignore into tags (game_id, tag_id) values (?, ?);", row...); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+	}
+	for _, ins := range inserts {
+		b.Run(ins.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				if _, err := db.Exec("delete from tags;"); err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+				if err := ins.insert(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+)
+
+// dropBlocked removes dismissed games from ids, keeping their order. Only
+// feeds over the whole catalog apply it; the watchlist shows what it lists.
+func dropBlocked(db *sql.DB, ids []int) ([]int, error) {
+	blocked, err := queryIDs(db, "select game_id from blocked;")
+	if err != nil {
+		return nil, err
+	}
+	if len(blocked) == 0 {
+		return ids, nil
+	}
+
+	skip := make(map[int]bool, len(blocked))
+	for _, id := range blocked {
+		skip[id] = true
+	}
+	out := []int{}
+	for _, id := range ids {
+		if !skip[id] {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+// serveBlock adds the game of the {id} path value to the blocklist, or
+// removes it
+func serveBlock(db *sql.DB, block bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil || id <= 0 {
+			writeError(w, "Invalid id", http.StatusBadRequest)
+			return
+		}
+
+		query := "insert into blocked (game_id) values (?) on conflict (game_id) do nothing;"
+		if !block {
+			query = "delete from blocked where game_id = ?;"
+		}
+		if _, err := db.Exec(query, id); err != nil {
+			writeError(w, "Error updating blocklist", http.StatusInternalServerError)
+			return
+		}
+		touchFeedState(db)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
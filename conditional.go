@@ -0,0 +1,98 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// maxUpdated returns the newest updated timestamp among the given games, or
+// the zero time when none of them are stored
+func maxUpdated(db *sql.DB, ids []int) (time.Time, error) {
+	if len(ids) == 0 {
+		return time.Time{}, nil
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+
+	var latest sql.NullString
+	err := db.QueryRow("select max(updated) from game where id in ("+placeholders+");", args...).Scan(&latest)
+	if err != nil || !latest.Valid {
+		return time.Time{}, err
+	}
+
+	return parseDBTime(latest.String)
+}
+
+// feedStateChanged is when pins, read marks or the blocklist last changed,
+// in unix seconds. They change the feeds without updating any game, and
+// removals leave no row behind to take a time from, so the time is kept
+// here and in meta across restarts.
+var feedStateChanged atomic.Int64
+
+// touchFeedState records that pins, read marks or the blocklist changed
+func touchFeedState(db *sql.DB) {
+	now := time.Now()
+	feedStateChanged.Store(now.Unix())
+	setMeta(db, "feed_state_changed", now.Format(sqliteTime))
+}
+
+// loadFeedState reads the time of the last pin, read or block change
+func loadFeedState(db *sql.DB) error {
+	v := getMeta(db, "feed_state_changed")
+	if v == "" {
+		return nil
+	}
+	changed, err := parseDBTime(v)
+	if err != nil {
+		return err
+	}
+	feedStateChanged.Store(changed.Unix())
+	return nil
+}
+
+// feedStateTime returns the time of the last pin, read or block change, or
+// the zero time when there was none
+func feedStateTime() time.Time {
+	if s := feedStateChanged.Load(); s != 0 {
+		return time.Unix(s, 0)
+	}
+	return time.Time{}
+}
+
+// feedModified returns when the feed of ids last changed: the newest of
+// the games' updates and the last pin, read or block change
+func feedModified(db *sql.DB, ids []int) (time.Time, error) {
+	latest, err := maxUpdated(db, ids)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if changed := feedStateTime(); changed.After(latest) {
+		latest = changed
+	}
+	return latest, nil
+}
+
+// notModified reports whether the client's If-Modified-Since covers
+// lastModified. If-None-Match takes precedence and is left to writeFeed.
+func notModified(r *http.Request, lastModified time.Time) bool {
+	if lastModified.IsZero() || r.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testFeed returns a feed of one item updated at updated
+func testFeed(updated time.Time) *RSS {
+	return newFeed(&Channel{Title: "Test", Link: "https://f95zone.to/latest"}, []*Item{{
+		Title:   "Game [0.1]",
+		Link:    "https://f95zone.to/threads/1",
+		PubDate: RSSDate{updated},
+	}})
+}
+
+func TestWriteFeedConditional(t *testing.T) {
+	updated := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	w := httptest.NewRecorder()
+	writeFeed(w, httptest.NewRequest(http.MethodGet, "/feed", nil), testFeed(updated))
+	etag := w.Header().Get("ETag")
+	length := w.Header().Get("Content-Length")
+	if w.Code != http.StatusOK || etag == "" || w.Body.Len() == 0 {
+		t.Fatalf("GET: status %d, ETag %q, %d bytes", w.Code, etag, w.Body.Len())
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		header   map[string]string
+		wantCode int
+		wantBody bool
+	}{
+		{"get", http.MethodGet, nil, http.StatusOK, true},
+		{"head", http.MethodHead, nil, http.StatusOK, false},
+		{"matching etag", http.MethodGet, map[string]string{"If-None-Match": etag}, http.StatusNotModified, false},
+		{"head with matching etag", http.MethodHead, map[string]string{"If-None-Match": etag}, http.StatusNotModified, false},
+		{"other etag", http.MethodGet, map[string]string{"If-None-Match": `"other"`}, http.StatusOK, true},
+		{"modified since", http.MethodGet, map[string]string{"If-Modified-Since": updated.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusOK, true},
+		{"not modified since", http.MethodGet, map[string]string{"If-Modified-Since": updated.Format(http.TimeFormat)}, http.StatusNotModified, false},
+		{"etag wins over date", http.MethodGet, map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": updated.Format(http.TimeFormat)}, http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/feed", nil)
+			for name, value := range tt.header {
+				r.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			writeFeed(w, r, testFeed(updated))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d", w.Code, tt.wantCode)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("ETag %q, want %q", got, etag)
+			}
+			if tt.wantBody != (w.Body.Len() > 0) {
+				t.Errorf("body of %d bytes, want body %v", w.Body.Len(), tt.wantBody)
+			}
+			// A 304 with an ETag leaves out Last-Modified, RFC 9110 15.4.5
+			if tt.wantCode == http.StatusOK {
+				if got := w.Header().Get("Last-Modified"); got != updated.Format(http.TimeFormat) {
+					t.Errorf("Last-Modified %q", got)
+				}
+				if got := w.Header().Get("Content-Length"); got != length {
+					t.Errorf("Content-Length %q, want %q", got, length)
+				}
+				if w.Header().Get("Content-Type") == "" {
+					t.Error("no Content-Type")
+				}
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	updated := time.Date(2024, 6, 1, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name     string
+		method   string
+		since    string
+		etag     string
+		modified time.Time
+		want     bool
+	}{
+		{"same second", http.MethodGet, updated.Format(http.TimeFormat), "", updated, true},
+		{"newer", http.MethodGet, updated.Add(-time.Second).Format(http.TimeFormat), "", updated, false},
+		{"head", http.MethodHead, updated.Format(http.TimeFormat), "", updated, true},
+		{"post", http.MethodPost, updated.Format(http.TimeFormat), "", updated, false},
+		{"etag present", http.MethodGet, updated.Format(http.TimeFormat), `"x"`, updated, false},
+		{"no header", http.MethodGet, "", "", updated, false},
+		{"unknown date", http.MethodGet, updated.Format(http.TimeFormat), "", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/feed", nil)
+			if tt.since != "" {
+				r.Header.Set("If-Modified-Since", tt.since)
+			}
+			if tt.etag != "" {
+				r.Header.Set("If-None-Match", tt.etag)
+			}
+			if got := notModified(r, tt.modified); got != tt.want {
+				t.Errorf("notModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFeedModifiedState checks a pin, read mark or block change moves the
+// feed's Last-Modified past its newest game, so If-Modified-Since misses
+func TestFeedModifiedState(t *testing.T) {
+	defer func(s int64) { feedStateChanged.Store(s) }(feedStateChanged.Load())
+	feedStateChanged.Store(0)
+
+	db := newTestDB(t)
+	ids := seedGames(t, db, 3, 1)
+	updated, err := feedModified(db, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	touchFeedState(db)
+	changed, err := feedModified(db, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed.After(updated) {
+		t.Fatalf("feedModified = %v after a state change, want after %v", changed, updated)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/feed", nil)
+	r.Header.Set("If-Modified-Since", updated.UTC().Format(http.TimeFormat))
+	if notModified(r, changed) {
+		t.Error("If-Modified-Since of the newest game matched after a state change")
+	}
+	w := httptest.NewRecorder()
+	writeFeed(w, r, testFeed(updated))
+	if w.Code != http.StatusOK {
+		t.Errorf("writeFeed: status %d after a state change, want 200", w.Code)
+	}
+
+	// The time survives a restart
+	feedStateChanged.Store(0)
+	if err := loadFeedState(db); err != nil {
+		t.Fatal(err)
+	}
+	if got := feedStateTime(); !got.Equal(changed) {
+		t.Errorf("loaded state time = %v, want %v", got, changed)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	configPath = flag.String("config", "", "JSON file of settings keyed by environment variable name")
+	runOnce    = flag.Bool("once", false, "update the database, write the feed file and exit")
+
+	configFile map[string]string   // settings of the -config file
+	configKeys = map[string]bool{} // every setting read through getenv
+)
+
+// loadConfig reads the -config file, then every setting from the
+// environment or the file, falling back to the defaults. main runs it once
+// the flags are parsed.
+func loadConfig() error {
+	var err error
+	if configFile, err = loadConfigFile(*configPath); err != nil {
+		return err
+	}
+
+	BASE_API = envOr("F95_RSS_API_URL", DEFAULT_API)
+	DBDRIVER = envOr("F95_RSS_DB_DRIVER", "sqlite")
+	DBFILE = getenv("F95_RSS_DB")
+	DBKEY = getenv("F95_RSS_DB_KEY")
+	IDFILE = getenv("F95_RSS_ID_FILE")
+	RSSCRON = getenv("F95_RSS_CRON")
+	APIQUERY = getenv("F95_RSS_API_QUERY")
+	WEBSUBHUB = getenv("F95_RSS_WEBSUB_HUB")
+	PUBLICURL = getenv("F95_RSS_PUBLIC_URL")
+	IMAGEURL = getenv("F95_RSS_IMAGE_URL")
+	SHOWCREATED = envBool("F95_RSS_SHOW_CREATED")
+	FEEDTZ = envLocation("F95_RSS_TZ")
+	JITTER = envDuration("F95_RSS_JITTER", 0)
+	OUTPUT = getenv("F95_RSS_OUTPUT")
+	OUTPUTGZIP = envBool("F95_RSS_OUTPUT_GZIP")
+	TTL = envInt("F95_RSS_TTL", 0)
+	OVERVIEWMAX = envInt("F95_RSS_OVERVIEW_MAX", 500)
+	MAXDESC = envInt("F95_RSS_MAX_DESC", 0)
+	ADDR = envOr("F95_RSS_ADDR", ":8080")
+	BASEPATH = envBasePath("F95_RSS_BASE_PATH")
+
+	APITIMEOUT = envDuration("F95_RSS_API_TIMEOUT", time.Minute)
+	APIMAXBYTES = envInt("F95_RSS_API_MAX_BYTES", 64<<20)
+	APIFILE = getenv("F95_RSS_API_FILE")
+	APIROWS = envInt("F95_RSS_API_ROWS", 0)
+
+	METAURL = getenv("F95_RSS_META_URL")
+	METACRON = envOr("F95_RSS_META_CRON", "@daily")
+
+	ALLOWTAGS = getenv("F95_RSS_ALLOW_TAGS")
+	ALLOWPREFIXES = getenv("F95_RSS_ALLOW_PREFIXES")
+	ALLOWFILE = getenv("F95_RSS_ALLOW_FILE")
+	MINTHREADID = envInt("F95_RSS_MIN_THREAD_ID", 0)
+	SKIPNOVERSION = envBool("F95_RSS_SKIP_NO_VERSION")
+	MINSCREENS = envInt("F95_RSS_MIN_SCREENS", 0)
+
+	WEBHOOKURL = getenv("F95_RSS_WEBHOOK_URL")
+	DISCORDWEBHOOK = getenv("F95_RSS_DISCORD_WEBHOOK")
+	TELEGRAMTOKEN = getenv("F95_RSS_TELEGRAM_TOKEN")
+	TELEGRAMCHAT = getenv("F95_RSS_TELEGRAM_CHAT")
+
+	QUIETHOURS = getenv("F95_RSS_QUIET_HOURS")
+
+	ADMINTOKEN = getenv("F95_RSS_ADMIN_TOKEN")
+
+	DEFAULTFORMAT = envOr("F95_RSS_DEFAULT_FORMAT", "rss")
+
+	GUIDMODE = envOr("F95_RSS_GUID", "version")
+
+	TITLETEMPLATE = envOr("F95_RSS_TITLE_TEMPLATE", defaultTitleTemplate)
+
+	FEEDHEADERS = getenv("F95_RSS_FEED_HEADERS")
+
+	IMAGEREWRITE = getenv("F95_RSS_IMAGE_REWRITE")
+
+	COVERTHUMBS = envBool("F95_RSS_COVER_THUMBS")
+
+	RELATEDMAX = envInt("F95_RSS_RELATED", 3)
+
+	STALEAFTER = envDuration("F95_RSS_STALE_AFTER", 0)
+
+	MAXGENERATIONS = envInt("F95_RSS_MAX_GENERATIONS", 4)
+	GENERATIONWAIT = envDuration("F95_RSS_GENERATION_WAIT", 2*time.Second)
+	MAXITEMS = envInt("F95_RSS_MAX_ITEMS", 500)
+	GENERATIONTIMEOUT = envDuration("F95_RSS_GENERATION_TIMEOUT", 10*time.Second)
+
+	MAXSSECLIENTS = envInt("F95_RSS_MAX_SSE_CLIENTS", 64)
+
+	IMGCACHE = envInt("F95_RSS_IMG_CACHE", 256)
+
+	IMGHOSTS = splitList([]string{envOr("F95_RSS_IMG_HOSTS", "f95zone.to,f95zone.com")})
+
+	INLINEMAX = envInt("F95_RSS_INLINE_MAX", 512<<10)
+
+	LISTS = getenv("F95_RSS_LISTS")
+
+	ACCESSLOG = envOr("F95_RSS_ACCESS_LOG", "true") != "false"
+
+	PRUNECRON = envOr("F95_RSS_PRUNE_CRON", "@weekly")
+
+	RUNHISTORY = envInt("F95_RSS_RUN_HISTORY", 100)
+
+	ALLOWDOWNGRADE = envBool("F95_RSS_ALLOW_DOWNGRADE")
+
+	// Values derived from the settings; validateConfig rejects invalid ones
+	apiClient = &http.Client{Timeout: APITIMEOUT}
+	generations = make(chan struct{}, max(MAXGENERATIONS, 1))
+	quietHours, _ = parseQuietHours(QUIETHOURS)
+	imageRewrites, _ = parseRewriteRules(IMAGEREWRITE)
+	return nil
+}
+
+// loadConfigFile reads a JSON config file of settings, none for an empty
+// path
+func loadConfigFile(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, v := range raw {
+		switch v := v.(type) {
+		case string:
+			values[key] = v
+		case float64, bool:
+			values[key] = fmt.Sprint(v)
+		case nil:
+		default:
+			return nil, fmt.Errorf("config file %s: %s must be a string, number or boolean", path, key)
+		}
+	}
+	return values, nil
+}
+
+// getenv reads a setting from the environment, then from the config file
+func getenv(key string) string {
+	configKeys[key] = true
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return configFile[key]
+}
+
+// validateConfig checks the merged settings needed to run
+func validateConfig() error {
+	var errs []error
+	for key := range configFile {
+		if !configKeys[key] {
+			errs = append(errs, fmt.Errorf("unknown setting %s in %s", key, *configPath))
+		}
+	}
+	if DBFILE == "" {
+		errs = append(errs, errors.New("F95_RSS_DB is not set"))
+	}
+	if d, ok := dialects[DBDRIVER]; ok {
+		sqlDialect = d
+	} else {
+		errs = append(errs, fmt.Errorf("F95_RSS_DB_DRIVER must be sqlite or postgres, got %q", DBDRIVER))
+	}
+	if DBDRIVER == "postgres" && DBKEY != "" {
+		errs = append(errs, errors.New("F95_RSS_DB_KEY only applies to SQLite databases"))
+	}
+	if IDFILE == "" {
+		errs = append(errs, errors.New("F95_RSS_ID_FILE is not set"))
+	}
+	if feedFormats[DEFAULTFORMAT] == "" {
+		errs = append(errs, fmt.Errorf("F95_RSS_DEFAULT_FORMAT %q is not one of rss, atom or json", DEFAULTFORMAT))
+	}
+	if _, err := parseRewriteRules(IMAGEREWRITE); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := feedHeaders(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := parseQuietHours(QUIETHOURS); err != nil {
+		errs = append(errs, err)
+	}
+	if t, err := parseTitleTemplate(TITLETEMPLATE); err != nil {
+		errs = append(errs, err)
+	} else {
+		titleTemplate = t
+	}
+	if (TELEGRAMTOKEN == "") != (TELEGRAMCHAT == "") {
+		errs = append(errs, errors.New("F95_RSS_TELEGRAM_TOKEN and F95_RSS_TELEGRAM_CHAT must be set together"))
+	}
+	if !guidModes[GUIDMODE] {
+		errs = append(errs, fmt.Errorf("F95_RSS_GUID %q is not one of thread, version or hash", GUIDMODE))
+	}
+	if _, err := watchlists(); err != nil {
+		errs = append(errs, err)
+	}
+	schedules := []struct{ key, spec string }{
+		{"F95_RSS_CRON", RSSCRON},
+		{"F95_RSS_META_CRON", METACRON},
+		{"F95_RSS_PRUNE_CRON", PRUNECRON},
+	}
+	if updatesDisabled() {
+		schedules = schedules[1:]
+	}
+	if *runOnce {
+		schedules = nil // -once never starts the scheduler
+	}
+	for _, s := range schedules {
+		if _, err := cron.ParseStandard(s.spec); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %w", s.key, s.spec, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// logConfig logs every setting that is set, hiding secrets
+func logConfig() {
+	keys := make([]string, 0, len(configKeys))
+	for key := range configKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		v, source := os.Getenv(key), "env"
+		if v == "" {
+			v, source = configFile[key], "config"
+		}
+		if v == "" {
+			continue
+		}
+		if strings.Contains(key, "TOKEN") || strings.Contains(key, "SECRET") || strings.Contains(key, "PASSWORD") || strings.HasSuffix(key, "_KEY") || key == "F95_RSS_DISCORD_WEBHOOK" {
+			v = "********"
+		}
+		if key == "F95_RSS_DB" {
+			v = redactDSN(v)
+		}
+		log.Printf("Config %s=%q (%s)", key, v, source)
+	}
+}
+
+// envBool reads a boolean environment variable; unset or invalid means false
+func envBool(key string) bool {
+	v := getenv(key)
+	if v == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("Ignoring invalid %s=%q: %v", key, v, err)
+		return false
+	}
+	return b
+}
+
+// envOr reads an environment variable, falling back to def when unset
+func envOr(key, def string) string {
+	if v := getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envBasePath reads a URL path prefix, returned without a trailing slash so
+// the root is ""
+func envBasePath(key string) string {
+	v := strings.Trim(getenv(key), "/")
+	if v == "" {
+		return ""
+	}
+	return "/" + v
+}
+
+// envLocation loads an IANA timezone name, falling back to the local zone
+func envLocation(key string) *time.Location {
+	v := getenv(key)
+	if v == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(v)
+	if err != nil {
+		log.Printf("Ignoring invalid %s=%q, using local time: %v", key, v, err)
+		return time.Local
+	}
+	return loc
+}
+
+// envDuration reads a time.ParseDuration value, falling back to def
+func envDuration(key string, def time.Duration) time.Duration {
+	v := getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Printf("Ignoring invalid %s=%q", key, v)
+		return def
+	}
+	return d
+}
+
+// envInt reads an integer environment variable, falling back to def
+func envInt(key string, def int) int {
+	v := getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Printf("Ignoring invalid %s=%q", key, v)
+		return def
+	}
+	return n
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Most creators /creators returns without ?limit=
+const creatorSearchLimit = 50
+
+// CreatorCount is a creator with the number of games they have
+type CreatorCount struct {
+	Name  string `json:"name"`
+	Games int    `json:"games"`
+}
+
+// likeEscaper escapes the wildcards of a like pattern, with \ as escape
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// normalizeCreator trims a creator name and collapses inner whitespace
+func normalizeCreator(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// creatorKey is the form creators are matched by, so "DevName" and
+// "devname " are the same creator
+func creatorKey(name string) string {
+	return strings.ToLower(normalizeCreator(name))
+}
+
+// mergeCreators fills creator.normalized, folds creators sharing a key into
+// the oldest row and makes the key unique
+func mergeCreators(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("select id, name from creator where normalized is null;")
+	if err != nil {
+		return err
+	}
+	keys := map[int]string{}
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		keys[id] = creatorKey(name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, key := range keys {
+		if _, err := tx.Exec("update creator set normalized = ? where id = ?;", key, id); err != nil {
+			return err
+		}
+	}
+
+	res, err := tx.Exec(`
+		update game set creator_id = (
+			select min(k.id) from creator c join creator k on k.normalized = c.normalized
+			where c.id = game.creator_id
+		)
+		where creator_id in (
+			select c.id from creator c join creator k on k.normalized = c.normalized and k.id < c.id
+		);
+	`)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("Merged duplicate creators of %d games", n)
+	}
+
+	_, err = tx.Exec(`
+		delete from creator where exists (
+			select 1 from creator k where k.normalized = creator.normalized and k.id < creator.id
+		);
+		create unique index if not exists creator_normalized on creator(normalized);
+	`)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// searchCreators returns up to limit creators whose name contains q, ignoring
+// case and spacing, those with the most games first
+func searchCreators(db *sql.DB, q string, limit int) ([]CreatorCount, error) {
+	query := `
+		select c.name, count(g.id) as games
+		from creator c
+		join game g on g.creator_id = c.id
+		where c.normalized like ? escape '\'
+		group by c.id
+		order by games desc, c.name
+		limit ?;
+	`
+	rows, err := db.Query(query, "%"+likeEscaper.Replace(creatorKey(q))+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	creators := []CreatorCount{}
+	for rows.Next() {
+		var c CreatorCount
+		if err := rows.Scan(&c.Name, &c.Games); err != nil {
+			return nil, err
+		}
+		creators = append(creators, c)
+	}
+	return creators, rows.Err()
+}
+
+// serveCreators lists the creators matching ?q= with their game counts.
+// ?limit=N caps the list.
+func serveCreators(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		if strings.TrimSpace(q) == "" {
+			writeError(w, "Missing q", http.StatusBadRequest)
+			return
+		}
+
+		limit := creatorSearchLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				writeError(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		creators, err := searchCreators(db, q, limit)
+		if err != nil {
+			writeError(w, "Error searching creators", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, creators)
+	}
+}
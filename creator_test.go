@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestCreatorKey(t *testing.T) {
+	tests := []struct {
+		in, normalized, key string
+	}{
+		{"DevName", "DevName", "devname"},
+		{"  DevName ", "DevName", "devname"},
+		{"Dev   Name", "Dev Name", "dev name"},
+		{"Dev\tName\n", "Dev Name", "dev name"},
+		{"ÉQUIPE", "ÉQUIPE", "équipe"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeCreator(tt.in); got != tt.normalized {
+			t.Errorf("normalizeCreator(%q) = %q, want %q", tt.in, got, tt.normalized)
+		}
+		if got := creatorKey(tt.in); got != tt.key {
+			t.Errorf("creatorKey(%q) = %q, want %q", tt.in, got, tt.key)
+		}
+	}
+}
+
+func TestInsertCreator(t *testing.T) {
+	db := newTestDB(t)
+
+	first := insertCreator(db, "DevName")
+	tests := []struct {
+		name string
+		same bool
+	}{
+		{"DevName", true},
+		{"devname", true},
+		{"DEVNAME", true},
+		{"Dev Name", false},
+		{"OtherDev", false},
+	}
+	for _, tt := range tests {
+		if got := insertCreator(db, tt.name); (got == first) != tt.same {
+			t.Errorf("insertCreator(%q) = %d, first was %d, want same %v", tt.name, got, first, tt.same)
+		}
+	}
+
+	// The first spelling seen is the one shown
+	var name string
+	if err := db.QueryRow("select name from creator where id = ?;", first).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != "DevName" {
+		t.Errorf("stored name %q, want DevName", name)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build sqlcipher
+
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	_ "github.com/mutecomm/go-sqlcipher/v4"
+)
+
+// Encrypted databases need the cgo SQLCipher driver instead of the pure Go
+// one. Build with a C compiler and:
+//
+//	CGO_ENABLED=1 go build -tags sqlcipher
+//
+// The driver bundles SQLCipher 4.4, on SQLite 3.33, so queries must not use
+// newer SQLite features such as RETURNING.
+//
+// and set F95_RSS_DB_KEY. New databases are created encrypted; existing
+// plain ones have to be exported with sqlcipher_export first.
+const dbDriver = "sqlite3"
+
+// dbDSN opens path with a busy timeout and immediate transactions, passing
+// the key as the PRAGMA key of every connection
+func dbDSN(path, key string) (string, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+
+	dsn := path + sep + "_busy_timeout=10000&_txlock=immediate"
+	if key != "" {
+		dsn += "&_pragma_key=" + url.QueryEscape(key)
+	}
+	return dsn, nil
+}
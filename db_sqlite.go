@@ -0,0 +1,27 @@
+//go:build !sqlcipher
+
+package main
+
+import (
+	"errors"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+const dbDriver = "sqlite"
+
+// dbDSN opens path with a busy timeout, and takes the write lock when a
+// transaction begins so concurrent starts queue instead of failing. This
+// driver can't decrypt databases, so a key is an error.
+func dbDSN(path, key string) (string, error) {
+	if key != "" {
+		return "", errors.New("F95_RSS_DB_KEY needs a build with -tags sqlcipher")
+	}
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + "_pragma=busy_timeout(10000)&_txlock=immediate", nil
+}
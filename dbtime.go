@@ -0,0 +1,71 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Timestamps are stored as local wall-clock time, which is what the column
+// defaults, datetime('now', 'localtime') and the update trigger produce.
+// The driver hands them back labelled UTC, so they are read with
+// parseDBTime.
+
+// Layout SQLite's datetime() and current_timestamp produce
+const sqliteTime = "2006-01-02 15:04:05"
+
+// updateTrigger keeps game.updated current. It once stored UTC while every
+// other timestamp was local, see localizeTimestamps.
+const updateTrigger = `
+	create trigger if not exists update_timestamp
+	after update on game
+	for each row
+	begin
+		update game
+		set updated = datetime(current_timestamp, 'localtime')
+		where id = old.id;
+	end;
+`
+
+// parseDBTime reads a stored timestamp, either as the driver formats
+// timestamp columns (RFC 3339) or as SQLite returns expressions such as
+// max(), and places its wall clock in the local zone
+func parseDBTime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		if t, err = time.Parse(sqliteTime, s); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local), nil
+}
+
+// localizeTimestamps replaces the UTC update trigger of older databases and
+// converts the values it wrote. Rows it never touched still have updated
+// equal to created, both local. It runs once, recorded in the meta table.
+// Postgres databases never had that trigger.
+func localizeTimestamps(db *sql.DB) error {
+	if DBDRIVER == "postgres" || getMeta(db, "timestamps") == "localtime" {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The trigger would overwrite the converted values
+	statements := []string{
+		`drop trigger if exists update_timestamp;`,
+		`update game set updated = datetime(updated, 'localtime') where updated != created;`,
+		updateTrigger,
+		`insert into meta (key, value) values ('timestamps', 'localtime')
+		on conflict (key) do update set value = excluded.value;`,
+	}
+	for _, s := range statements {
+		if _, err := tx.Exec(s); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDBTime(t *testing.T) {
+	want := time.Date(2024, 6, 1, 12, 30, 45, 0, time.Local)
+
+	tests := []struct {
+		name, in string
+		wantErr  bool
+	}{
+		{"driver format", "2024-06-01T12:30:45Z", false},
+		{"sqlite format", "2024-06-01 12:30:45", false},
+		{"empty", "", true},
+		{"garbage", "yesterday", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDBTime(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDBTime(%q) error %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(want) {
+				t.Errorf("parseDBTime(%q) = %v, want %v", tt.in, got, want)
+			}
+		})
+	}
+}
+
+// TestTimestampRoundTrip inserts and updates a game and reads its
+// timestamps back the way the feed does, expecting the current time
+// rather than one shifted by the zone offset
+func TestTimestampRoundTrip(t *testing.T) {
+	db := newTestDB(t)
+
+	read := func() (created, updated time.Time) {
+		t.Helper()
+		var c, u string
+		if err := db.QueryRow("select created, updated from game where id = 1;").Scan(&c, &u); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		if created, err = parseDBTime(c); err != nil {
+			t.Fatal(err)
+		}
+		if updated, err = parseDBTime(u); err != nil {
+			t.Fatal(err)
+		}
+		return created, updated
+	}
+	near := func(what string, got time.Time) {
+		t.Helper()
+		if d := time.Since(got); d < -2*time.Second || d > 2*time.Second {
+			t.Errorf("%s is %v, %v away from now", what, got, d)
+		}
+	}
+
+	insertGame(db, 1, "Game", "0.1", 4, "", 0)
+	created, updated := read()
+	near("created after insert", created)
+	near("updated after insert", updated)
+
+	// The trigger sets updated on every update
+	time.Sleep(1100 * time.Millisecond)
+	insertGame(db, 1, "Game", "0.2", 4, "", 0)
+	created2, updated2 := read()
+	if !created2.Equal(created) {
+		t.Errorf("created changed from %v to %v", created, created2)
+	}
+	near("updated after update", updated2)
+	if !updated2.After(updated) {
+		t.Errorf("updated went from %v to %v", updated, updated2)
+	}
+
+	latest, err := maxUpdated(db, []int{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !latest.Equal(updated2) {
+		t.Errorf("maxUpdated = %v, want %v", latest, updated2)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	dbCheckInterval  = 30 * time.Second // how often the database file is checked
+	dbReconnectTries = 5                // reopen attempts before giving up
+)
+
+// dbOpened is the database file the pool's connections refer to, guarded
+// by updateMu
+var dbOpened os.FileInfo
+
+// checkDatabase runs a trivial query that fails on a missing or corrupt file
+func checkDatabase(db *sql.DB) error {
+	var tables int
+	return db.QueryRow("select count(*) from (" + sqlDialect.tablesQuery() + ") t;").Scan(&tables)
+}
+
+// baseTables are the tables createTables makes, which every query relies on
+var baseTables = []string{"creator", "game", "host", "cover", "preview", "tags", "prefixes"}
+
+// missingTables lists the base tables the database lacks, e.g. because the
+// file is empty or was truncated
+func missingTables(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(sqlDialect.tablesQuery() + ";")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	have := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		have[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, name := range baseTables {
+		if !have[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// restoreSchema creates the schema again when tables went missing from a
+// readable database. The caller must hold updateMu.
+func restoreSchema(db *sql.DB) {
+	missing, err := missingTables(db)
+	if err != nil || len(missing) == 0 {
+		return
+	}
+
+	log.Printf("Database is missing tables %s, creating the schema", strings.Join(missing, ", "))
+	createDatabase(db)
+	migrateDatabase(db)
+	resetTagCounts()
+}
+
+// watchDatabase periodically runs ensureDatabase, so a replaced file is
+// picked up between updates too
+func watchDatabase(db *sql.DB) {
+	for range time.Tick(dbCheckInterval) {
+		updateMu.Lock()
+		ensureDatabase(db)
+		updateMu.Unlock()
+	}
+}
+
+// ensureDatabase notices the database file being replaced, e.g. by a backup
+// restore, or becoming unreadable, and reconnects. Open connections keep
+// using the old file, so they are dropped and new ones opened from the DSN,
+// which re-applies the pragmas. A Postgres server has no file to replace,
+// so only the check applies. The caller must hold updateMu.
+func ensureDatabase(db *sql.DB) {
+	if DBDRIVER == "postgres" {
+		if err := checkDatabase(db); err != nil {
+			log.Printf("Database check failed, reconnecting: %v", err)
+			reconnectDatabase(db)
+			return
+		}
+		restoreSchema(db)
+		return
+	}
+
+	fi, err := os.Stat(DBFILE)
+	if err != nil {
+		log.Printf("Database file unavailable: %v", err)
+		return
+	}
+
+	switch {
+	case dbOpened == nil:
+		dbOpened = fi
+		return
+	case !os.SameFile(dbOpened, fi):
+		log.Println("Database file was replaced, reconnecting...")
+	default:
+		err := checkDatabase(db)
+		if err == nil {
+			restoreSchema(db)
+			return
+		}
+		log.Printf("Database check failed, reconnecting: %v", err)
+	}
+
+	reconnectDatabase(db)
+	dbOpened = fi
+}
+
+// reconnectDatabase reopens the pool's connections with backoff and brings
+// the schema of the new file up to date. It exits after dbReconnectTries
+// failed attempts.
+func reconnectDatabase(db *sql.DB) {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		// Dropping the idle limit closes every idle connection
+		db.SetMaxIdleConns(0)
+		db.SetMaxIdleConns(2)
+
+		err := checkDatabase(db)
+		if err == nil {
+			break
+		}
+		if attempt == dbReconnectTries {
+			log.Fatalf("Failed to reconnect to the database after %d attempts: %v", attempt, err)
+		}
+
+		log.Printf("Reconnect attempt %d failed, retrying in %s: %v", attempt, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	createDatabase(db)
+	migrateDatabase(db)
+	resetTagCounts()
+	log.Println("Reconnected to the database")
+}
@@ -0,0 +1,54 @@
+package main
+
+// dialect is the SQL that differs between the supported databases. The rest
+// of the queries stick to what both understand: ? placeholders, which the
+// Postgres driver rebinds, on conflict clauses instead of insert or ignore,
+// and timestamps stored as local time text.
+type dialect interface {
+	// schema adapts a create statement written for SQLite
+	schema(stmt string) string
+	// extraSchema runs after the base tables, e.g. the update trigger
+	extraSchema() string
+	// tablesQuery selects the names of the tables
+	tablesQuery() string
+	// columnQuery counts the columns of table ? named ?
+	columnQuery() string
+	// dropTable drops a table together with what depends on it
+	dropTable(name string) string
+}
+
+// sqlDialect is the dialect of DBDRIVER, set by validateConfig
+var sqlDialect dialect = sqliteDialect{}
+
+var dialects = map[string]dialect{
+	"sqlite":   sqliteDialect{},
+	"postgres": postgresDialect{},
+}
+
+// dbSource returns the database/sql driver name and DSN of F95_RSS_DB
+func dbSource() (string, string, error) {
+	if DBDRIVER == "postgres" {
+		return pgDriverName, DBFILE, nil
+	}
+	dsn, err := dbDSN(DBFILE, DBKEY)
+	return dbDriver, dsn, err
+}
+
+// sqliteDialect is the SQL the schema and queries are written in
+type sqliteDialect struct{}
+
+func (sqliteDialect) schema(stmt string) string { return stmt }
+
+func (sqliteDialect) extraSchema() string { return updateTrigger }
+
+func (sqliteDialect) tablesQuery() string {
+	return "select name from sqlite_master where type = 'table' and name not like 'sqlite_%'"
+}
+
+func (sqliteDialect) columnQuery() string {
+	return "select count(*) from pragma_table_info(?) where name = ?;"
+}
+
+func (sqliteDialect) dropTable(name string) string {
+	return `drop table "` + name + `";`
+}
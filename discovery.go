@@ -0,0 +1,80 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Default look-back window for the new creators feed
+const newCreatorDays = 30
+
+// fetchDebutGames returns the first game of every creator whose earliest
+// game was ingested within the last days, newest creators first
+func fetchDebutGames(db *sql.DB, days int) ([]int, error) {
+	query := `
+		select min(g.id) from game g
+		where g.created = (
+			select min(created) from game where creator_id = g.creator_id
+		)
+		and g.created >= ?
+		group by g.creator_id
+		order by max(g.created) desc;
+	`
+
+	since := time.Now().AddDate(0, 0, -days).Format(sqliteTime)
+	rows, err := db.Query(query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// Serve a discovery feed of debut games from recently seen creators
+func serveNewCreators(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		days := newCreatorDays
+		if v := r.URL.Query().Get("days"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				http.Error(w, "Invalid days parameter", http.StatusBadRequest)
+				return
+			}
+			days = n
+		}
+
+		ids, err := fetchDebutGames(db, days)
+		if err == nil {
+			ids, err = dropBlocked(db, ids)
+		}
+		if err != nil {
+			http.Error(w, "Error finding new creators", http.StatusInternalServerError)
+			return
+		}
+		ids = capItems(w, ids)
+
+		items, err := fetchDataFromDB(r.Context(), db, ids, parseFeedOptions(r))
+		if err != nil {
+			feedError(w, err, "Error generating feed")
+			return
+		}
+
+		writeFeed(w, r, newFeed(&Channel{
+			Title:       "F95zone New Creators",
+			Link:        "https://f95zone.com/latest",
+			Description: "Debut games from creators first seen in the last " + strconv.Itoa(days) + " days",
+		}, items))
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var MAXSSECLIENTS int // concurrent /events connections
+
+// sseHeartbeat keeps idle connections open through proxies
+const sseHeartbeat = 30 * time.Second
+
+// UpdateEvent announces a new version of a watched game
+type UpdateEvent struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Version string `json:"version"`
+	Link    string `json:"link"`
+}
+
+// events fans update events out to the connected /events clients
+var events = struct {
+	sync.Mutex
+	clients map[chan []byte]bool
+}{clients: map[chan []byte]bool{}}
+
+// subscribe registers a client, or returns nil when all slots are taken
+func subscribe() chan []byte {
+	events.Lock()
+	defer events.Unlock()
+	if MAXSSECLIENTS > 0 && len(events.clients) >= MAXSSECLIENTS {
+		return nil
+	}
+	ch := make(chan []byte, 16)
+	events.clients[ch] = true
+	return ch
+}
+
+func unsubscribe(ch chan []byte) {
+	events.Lock()
+	delete(events.clients, ch)
+	events.Unlock()
+}
+
+// publishUpdates sends an event for every changed game in the watchlist.
+// Slow clients miss events rather than holding up the update.
+func publishUpdates(db *sql.DB, watched, changed []int) {
+	events.Lock()
+	defer events.Unlock()
+	if len(events.clients) == 0 {
+		return
+	}
+
+	watch := make(map[int]bool, len(watched))
+	for _, id := range watched {
+		watch[id] = true
+	}
+
+	for _, id := range changed {
+		if !watch[id] {
+			continue
+		}
+
+		e := UpdateEvent{ID: id, Link: fmt.Sprintf("https://f95zone.to/threads/%d", id)}
+		err := db.QueryRow("select title, coalesce(version, '') from game where id = ?;", id).Scan(&e.Title, &e.Version)
+		if err != nil {
+			log.Printf("Failed to load game %d for events: %v", id, err)
+			continue
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			continue
+		}
+
+		for ch := range events.clients {
+			select {
+			case ch <- data:
+			default:
+			}
+		}
+	}
+}
+
+// serveEvents streams update events as Server-Sent Events
+func serveEvents(w http.ResponseWriter, r *http.Request) {
+	ch := subscribe()
+	if ch == nil {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "Too many event clients", http.StatusServiceUnavailable)
+		return
+	}
+	defer unsubscribe(ch)
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data := <-ch:
+			fmt.Fprintf(w, "event: update\ndata: %s\n\n", data)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvCell keeps spreadsheets from running a scraped text as a formula by
+// prefixing a quote to cells starting with a formula character
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// Serve the watched games, or the whole catalog with ?all=1, as CSV. The
+// feed's filter parameters apply.
+func serveExportCSV(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var ids []int
+		if r.URL.Query().Get("all") == "1" {
+			ids, err = searchIDs(db, filter, -1)
+		} else {
+			ids, err = readIDsFromFile(IDFILE)
+			if err == nil {
+				ids, err = filterIDs(db, filter, ids)
+			}
+		}
+		if err != nil {
+			http.Error(w, "Error selecting games", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="f95-games.csv"`)
+
+		out := csv.NewWriter(w)
+		out.Write([]string{"id", "title", "version", "creator", "updated", "rating"})
+		for _, id := range ids {
+			g, err := fetchGameDetail(db, id)
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				// The response has started, so all that's left is to stop
+				log.Printf("CSV export failed at game %d: %v", id, err)
+				out.Flush()
+				return
+			}
+
+			out.Write([]string{
+				strconv.Itoa(g.ID),
+				csvCell(g.Title),
+				csvCell(g.Version),
+				csvCell(g.Creator),
+				g.Updated.Format(time.RFC3339),
+				strconv.FormatFloat(g.Rating, 'f', -1, 64),
+			})
+		}
+		out.Flush()
+	}
+}
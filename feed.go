@@ -0,0 +1,65 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"net/http"
+)
+
+// FeedOptions are the per-request rendering switches of a feed
+type FeedOptions struct {
+	Inline bool   // embed covers as data: URIs
+	Lang   string // language of the channel texts
+
+	ScreenItems bool // emit every screenshot as its own item
+	Compact     bool // titles and links only
+	Unread      bool // leave out items marked read
+
+	Summary bool // lead with an item counting the games per status
+}
+
+// parseFeedOptions reads feed options from the query string
+func parseFeedOptions(r *http.Request) FeedOptions {
+	q := r.URL.Query()
+	return FeedOptions{
+		Inline: q.Get("inline") == "1",
+		Lang:   preferredLanguage(r),
+
+		ScreenItems: q.Get("screens") == "items",
+		Compact:     q.Get("compact") == "1",
+		Unread:      q.Get("unread") == "1",
+
+		Summary: q.Get("summary") == "1",
+	}
+}
+
+// withScreenItems follows every game item with one item per screenshot,
+// for readers that present feeds as image galleries
+func withScreenItems(db *sql.DB, items []*Item) ([]*Item, error) {
+	var out []*Item
+	for _, item := range items {
+		out = append(out, item)
+
+		screens, err := fetchPreviews(db, item.GameID)
+		if err != nil {
+			return nil, err
+		}
+
+		for n, u := range screens {
+			out = append(out, &Item{
+				Title:       fmt.Sprintf("%s (screenshot %d/%d)", item.Name, n+1, len(screens)),
+				Link:        item.Link,
+				Description: "<img src=\"" + html.EscapeString(rewriteImageURL(u)) + "\" alt=\"" + html.EscapeString(item.Name) + "\" />",
+				GUID:        &GUID{Value: fmt.Sprintf("f95-%d-screen-%d", item.GameID, n+1), IsPermaLink: "false"},
+				PubDate:     item.PubDate,
+				Created:     item.Created,
+				GameID:      item.GameID,
+				Name:        item.Name,
+				Version:     item.Version,
+				Cover:       u,
+			})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Most tags one name prefix expands to
+const tagPrefixMax = 50
+
+// f95zone's prefix ids for the game status
+var statusPrefixes = map[string]int{
+	"completed": 18,
+	"onhold":    20,
+	"abandoned": 22,
+}
+
+// FeedFilter narrows the games of a feed
+type FeedFilter struct {
+	Tags      []int    // games must carry all of these tags
+	TagNames  []string // for each name prefix, games must carry a tag whose name starts with it
+	NotTags   []int    // games must carry none of these tags
+	MinRating float64  // minimum rating, 0 for any
+	Status    []string // any of completed, onhold, abandoned or ongoing
+
+	SinceVersion   string // only games whose version sorts above this one
+	RequireVersion bool   // leave out games without a version
+	HasScreens     bool   // leave out games without screenshots
+	Date           string // only games updated on this day of FEEDTZ, as 2006-01-02
+}
+
+// parseFilter reads a filter from query parameters. Lists may be repeated
+// or comma separated: ?tag=1,2&notag=3&min_rating=4&status=completed&require_version=1&has_screens=1&date=2024-06-01
+//
+// A tag ending in * is a case-insensitive name prefix, matching games with
+// any tag whose name starts with it, e.g. ?tag=3d* or ?tag_prefix=3d. Only
+// tags with a known name match, and a prefix expands to at most
+// tagPrefixMax of them.
+func parseFilter(q url.Values) (f FeedFilter, err error) {
+	var tags []string
+	for _, s := range splitList(q["tag"]) {
+		if prefix, ok := strings.CutSuffix(s, "*"); ok {
+			if prefix == "" {
+				return f, fmt.Errorf("invalid tag %q: empty prefix", s)
+			}
+			f.TagNames = append(f.TagNames, prefix)
+			continue
+		}
+		tags = append(tags, s)
+	}
+	if f.Tags, err = parseIntList(tags); err != nil {
+		return f, fmt.Errorf("invalid tag: %w", err)
+	}
+	f.TagNames = append(f.TagNames, splitList(q["tag_prefix"])...)
+	if f.NotTags, err = parseIntList(q["notag"]); err != nil {
+		return f, fmt.Errorf("invalid notag: %w", err)
+	}
+
+	if v := q.Get("min_rating"); v != "" {
+		f.MinRating, err = strconv.ParseFloat(v, 64)
+		if err != nil || f.MinRating < 0 || f.MinRating > 5 {
+			return f, fmt.Errorf("invalid min_rating %q", v)
+		}
+	}
+
+	for _, s := range splitList(q["status"]) {
+		s = strings.ToLower(s)
+		if _, ok := statusPrefixes[s]; !ok && s != "ongoing" {
+			return f, fmt.Errorf("invalid status %q", s)
+		}
+		f.Status = append(f.Status, s)
+	}
+
+	if v := q.Get("since_version"); v != "" {
+		f.SinceVersion = normalizeVersion(v)
+		if !hasDigits(versionChunks(f.SinceVersion)) {
+			return f, fmt.Errorf("invalid since_version %q", v)
+		}
+	}
+	f.RequireVersion = q.Get("require_version") == "1"
+	f.HasScreens = q.Get("has_screens") == "1"
+
+	if v := q.Get("date"); v != "" {
+		if _, err := time.ParseInLocation(time.DateOnly, v, FEEDTZ); err != nil {
+			return f, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", v)
+		}
+		f.Date = v
+	}
+
+	return f, nil
+}
+
+// dayRange returns the bounds of a FEEDTZ calendar day as stored
+// timestamps, which are local time, for a half-open range query
+func dayRange(date string) (string, string) {
+	start, _ := time.ParseInLocation(time.DateOnly, date, FEEDTZ)
+	end := start.AddDate(0, 0, 1)
+	return start.In(time.Local).Format(sqliteTime), end.In(time.Local).Format(sqliteTime)
+}
+
+// Encode returns the filter as query parameters accepted by parseFilter
+func (f FeedFilter) Encode() string {
+	q := url.Values{}
+	for _, id := range f.Tags {
+		q.Add("tag", strconv.Itoa(id))
+	}
+	for _, prefix := range f.TagNames {
+		q.Add("tag_prefix", prefix)
+	}
+	for _, id := range f.NotTags {
+		q.Add("notag", strconv.Itoa(id))
+	}
+	if f.MinRating > 0 {
+		q.Set("min_rating", strconv.FormatFloat(f.MinRating, 'f', -1, 64))
+	}
+	for _, s := range f.Status {
+		q.Add("status", s)
+	}
+	if f.SinceVersion != "" {
+		q.Set("since_version", f.SinceVersion)
+	}
+	if f.RequireVersion {
+		q.Set("require_version", "1")
+	}
+	if f.HasScreens {
+		q.Set("has_screens", "1")
+	}
+	if f.Date != "" {
+		q.Set("date", f.Date)
+	}
+	return q.Encode()
+}
+
+// IsZero reports whether the filter matches every game
+func (f FeedFilter) IsZero() bool {
+	return len(f.Tags) == 0 && len(f.TagNames) == 0 && len(f.NotTags) == 0 && f.MinRating == 0 && len(f.Status) == 0 && f.SinceVersion == "" && !f.RequireVersion && !f.HasScreens && f.Date == ""
+}
+
+// where renders the filter as SQL conditions on the game alias g
+func (f FeedFilter) where() (string, []any) {
+	var conds []string
+	var args []any
+
+	// Required tags seed the search from the tags_tag index, which beats
+	// walking the games in update order when few of them match
+	for _, id := range f.Tags {
+		conds = append(conds, "g.id in (select t.game_id from tags t where t.tag_id = ?)")
+		args = append(args, id)
+	}
+	for _, prefix := range f.TagNames {
+		conds = append(conds, `g.id in (select t.game_id from tags t where t.tag_id in (
+			select n.id from tag n where lower(n.name) like lower(?) escape '\' order by n.id limit ?
+		))`)
+		args = append(args, likeEscaper.Replace(prefix)+"%", tagPrefixMax)
+	}
+	for _, id := range f.NotTags {
+		conds = append(conds, "not exists (select 1 from tags t where t.game_id = g.id and t.tag_id = ?)")
+		args = append(args, id)
+	}
+	if f.MinRating > 0 {
+		conds = append(conds, "coalesce(g.rating, 0) >= ?")
+		args = append(args, f.MinRating)
+	}
+
+	if len(f.Status) > 0 {
+		var alts []string
+		for _, s := range f.Status {
+			if s == "ongoing" {
+				alts = append(alts, "not exists (select 1 from prefixes p where p.game_id = g.id and p.prefix_id in (?, ?, ?))")
+				args = append(args, statusPrefixes["completed"], statusPrefixes["onhold"], statusPrefixes["abandoned"])
+				continue
+			}
+			alts = append(alts, "exists (select 1 from prefixes p where p.game_id = g.id and p.prefix_id = ?)")
+			args = append(args, statusPrefixes[s])
+		}
+		conds = append(conds, "("+strings.Join(alts, " or ")+")")
+	}
+
+	if f.RequireVersion {
+		conds = append(conds, "coalesce(g.version, '') <> ''")
+	}
+	if f.HasScreens {
+		conds = append(conds, "exists (select 1 from preview p where p.game_id = g.id)")
+	}
+
+	if f.Date != "" {
+		start, end := dayRange(f.Date)
+		conds = append(conds, "g.updated >= ? and g.updated < ?")
+		args = append(args, start, end)
+	}
+
+	if len(conds) == 0 {
+		return "1 = 1", nil
+	}
+	return strings.Join(conds, " and "), args
+}
+
+// filterIDs keeps the ids matching f, preserving their order
+func filterIDs(db *sql.DB, f FeedFilter, ids []int) ([]int, error) {
+	if f.IsZero() || len(ids) == 0 {
+		return ids, nil
+	}
+
+	matched, err := matchIDs(db, f, false, "", -1)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := make(map[int]bool, len(matched))
+	for _, id := range matched {
+		keep[id] = true
+	}
+
+	var out []int
+	for _, id := range ids {
+		if keep[id] {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+// searchIDs returns up to limit ids of the whole catalog matching f, most
+// recently updated first, leaving out blocked games. A negative limit
+// returns every match.
+func searchIDs(db *sql.DB, f FeedFilter, limit int) ([]int, error) {
+	return matchIDs(db, f, true, "order by g.updated desc", limit)
+}
+
+// matchIDs selects the games matching f, without the blocked ones when
+// unblocked is set. Version thresholds can't be expressed in SQL, so they
+// are applied to the selected rows.
+func matchIDs(db *sql.DB, f FeedFilter, unblocked bool, order string, limit int) ([]int, error) {
+	where, args := f.where()
+	if unblocked {
+		where += " and g.id not in (select game_id from blocked)"
+	}
+	query := "select g.id, coalesce(g.version, '') from game g where " + where + " " + order
+	if f.SinceVersion == "" && limit >= 0 {
+		query += " limit ?"
+		args = append(args, limit)
+	}
+
+	rows, err := db.Query(query+";", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		var version string
+		if err := rows.Scan(&id, &version); err != nil {
+			return nil, err
+		}
+		if f.SinceVersion != "" && compareVersions(version, f.SinceVersion) <= 0 {
+			continue
+		}
+		if limit >= 0 && len(ids) >= limit {
+			break
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func splitList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+func parseIntList(values []string) ([]int, error) {
+	var out []int
+	for _, s := range splitList(values) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// BenchmarkFeedFilters serves filtered /feed requests from 20k games with 8
+// tags each, and runs their filter query alone, with and without the
+// filter indexes
+func BenchmarkFeedFilters(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	db := newTestDB(b)
+	ids := seedGames(b, db, 20000, 8)
+	writeIDFile(b, ids)
+	handler := serveFeed(db)
+
+	queries := []struct{ name, query string }{
+		{"two tags and min_rating", "tag=3,10&min_rating=4&limit=100"},
+		{"one tag", "tag=3&limit=100"},
+		{"date", "date=2024-03-01&limit=100"},
+	}
+	run := func(b *testing.B) {
+		for _, q := range queries {
+			r := httptest.NewRequest(http.MethodGet, "/feed?"+q.query, nil)
+			b.Run(q.name+"/feed", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					w := httptest.NewRecorder()
+					handler(w, r)
+					if w.Code != http.StatusOK {
+						b.Fatalf("status %d: %s", w.Code, w.Body)
+					}
+				}
+			})
+			b.Run(q.name+"/filter", func(b *testing.B) {
+				f, err := parseFilter(r.URL.Query())
+				if err != nil {
+					b.Fatal(err)
+				}
+				for i := 0; i < b.N; i++ {
+					if _, err := filterIDs(db, f, ids); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+
+	b.Run("indexed", run)
+	for _, index := range []string{"game_updated", "game_rating", "tags_tag"} {
+		if _, err := db.Exec("drop index " + index + ";"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.Run("unindexed", run)
+}
+
+// TestFeedRequireVersion checks games without a version are dropped before
+// paging, so the count and the pages only cover games that are served
+func TestFeedRequireVersion(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	db := newTestDB(t)
+	ids := seedGames(t, db, 10, 1)
+	writeIDFile(t, ids)
+	if _, err := db.Exec("update game set version = '' where id <= 6;"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/feed?require_version=1&limit=2", nil)
+	w := httptest.NewRecorder()
+	serveFeed(db)(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if got := w.Header().Get("X-Total-Count"); got != "4" {
+		t.Errorf("X-Total-Count = %q, want 4", got)
+	}
+	if got := strings.Count(w.Body.String(), "<item>"); got != 2 {
+		t.Errorf("got %d items, want 2", got)
+	}
+}
+
+// TestFeedHasScreens checks games without screenshots are dropped before
+// paging, and that no match is still a valid empty feed
+func TestFeedHasScreens(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	db := newTestDB(t)
+	ids := seedGames(t, db, 10, 1)
+	writeIDFile(t, ids)
+
+	serve := func() *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/feed?has_screens=1&limit=2", nil)
+		w := httptest.NewRecorder()
+		serveFeed(db)(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		return w
+	}
+
+	if w := serve(); strings.Contains(w.Body.String(), "<item>") || !strings.Contains(w.Body.String(), "<channel>") {
+		t.Errorf("want an empty feed, got %s", w.Body)
+	}
+
+	insertPreview(db, 9, []string{"https://attachments.f95zone.to/a.jpg"})
+	insertPreview(db, 10, []string{"https://attachments.f95zone.to/b.jpg"})
+	insertPreview(db, 3, []string{"https://attachments.f95zone.to/c.jpg"})
+	w := serve()
+	if got := w.Header().Get("X-Total-Count"); got != "3" {
+		t.Errorf("X-Total-Count = %q, want 3", got)
+	}
+	if got := strings.Count(w.Body.String(), "<item>"); got != 2 {
+		t.Errorf("got %d items, want 2", got)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DEFAULTFORMAT is what feeds are served as when neither the path nor the
+// Accept header asks for a format
+var DEFAULTFORMAT string // rss, atom or json
+
+// feedFormats maps each format to its media type
+var feedFormats = map[string]string{
+	"rss":  "application/rss+xml",
+	"atom": "application/atom+xml",
+	"json": "application/feed+json",
+}
+
+// acceptedFormats maps the media types a reader may ask for to a format
+var acceptedFormats = map[string]string{
+	"application/rss+xml":   "rss",
+	"application/atom+xml":  "atom",
+	"application/feed+json": "json",
+	"application/json":      "json",
+}
+
+// feedFormat picks the format of a feed response: a .rss, .atom or .json
+// extension wins, then the preferred media type of the Accept header, then
+// DEFAULTFORMAT. negotiated is set when the Accept header was consulted.
+func feedFormat(r *http.Request) (format string, negotiated bool) {
+	if ext := strings.TrimPrefix(path.Ext(r.URL.Path), "."); feedFormats[ext] != "" {
+		return ext, false
+	}
+
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		f, ok := acceptedFormats[mediaType]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > bestQ {
+			best, bestQ = f, q
+		}
+	}
+	if best != "" {
+		return best, true
+	}
+	return DEFAULTFORMAT, true
+}
+
+// marshalFeed renders feed in format, returning the body and its media type
+func marshalFeed(feed *RSS, format, self string) ([]byte, string, error) {
+	switch format {
+	case "atom":
+		data, err := xml.MarshalIndent(atomFeed(feed, self), "", "  ")
+		return append([]byte(xml.Header), data...), feedFormats["atom"] + "; charset=utf-8", err
+	case "json":
+		data, err := json.MarshalIndent(jsonFeed(feed, self), "", "  ")
+		return data, feedFormats["json"] + "; charset=utf-8", err
+	default:
+		data, err := xml.MarshalIndent(feed, "", "  ")
+		return data, "application/xml", err
+	}
+}
+
+// AtomFeed is the Atom rendering of a channel
+type AtomFeed struct {
+	XMLName  xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang     string       `xml:"xml:lang,attr,omitempty"`
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Subtitle string       `xml:"subtitle,omitempty"`
+	Updated  string       `xml:"updated"`
+	Author   *AtomPerson  `xml:"author"`
+	Links    []AtomLink   `xml:"link"`
+	Icon     string       `xml:"icon,omitempty"`
+	Logo     string       `xml:"logo,omitempty"`
+	Entries  []*AtomEntry `xml:"entry"`
+}
+
+// AtomEntry is the Atom rendering of an item
+type AtomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Author     *AtomPerson    `xml:"author,omitempty"`
+	Links      []AtomLink     `xml:"link"`
+	Published  string         `xml:"published,omitempty"`
+	Updated    string         `xml:"updated"`
+	Categories []AtomCategory `xml:"category"`
+	Content    *AtomContent   `xml:"content,omitempty"`
+}
+
+type AtomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type AtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type AtomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// creatorURL links the games of a creator on f95zone. The API only knows
+// creators by name, so this is the latest updates page filtered to them
+// rather than their profile.
+func creatorURL(name string) string {
+	return "https://f95zone.to/sam/latest_alpha/#/cat=games/page=1/creator=" + url.PathEscape(name)
+}
+
+// atomID turns a guid into the IRI Atom requires; thread URL guids already
+// are one
+func atomID(guid string) string {
+	if strings.HasPrefix(guid, "https://") {
+		return guid
+	}
+	return "tag:f95zone.to,2024:" + guid
+}
+
+func atomFeed(feed *RSS, self string) *AtomFeed {
+	c := feed.Channel
+	a := &AtomFeed{
+		Lang:     c.Language,
+		ID:       self,
+		Title:    c.Title,
+		Subtitle: c.Description,
+		// Atom needs an author on every entry, which the feed level one
+		// provides for games without a creator
+		Author: &AtomPerson{Name: "F95zone", URI: c.Link},
+		Links: []AtomLink{
+			{Href: self, Rel: "self", Type: feedFormats["atom"]},
+			{Href: c.Link, Rel: "alternate", Type: "text/html"},
+		},
+	}
+	for _, l := range c.AtomLinks {
+		if l.Rel == "hub" {
+			a.Links = append(a.Links, *l)
+		}
+	}
+	if c.Image != nil {
+		a.Icon = c.Image.URL
+		a.Logo = c.Image.URL
+	}
+
+	var updated time.Time
+	for _, item := range c.Items {
+		e := &AtomEntry{
+			ID:      atomID(itemID(item)),
+			Title:   item.Title,
+			Links:   []AtomLink{{Href: item.Link, Rel: "alternate", Type: "text/html"}},
+			Updated: item.PubDate.Format(time.RFC3339),
+		}
+		if item.Creator != "" {
+			e.Author = &AtomPerson{Name: item.Creator, URI: creatorURL(item.Creator)}
+		}
+		if !item.Created.IsZero() {
+			e.Published = item.Created.Format(time.RFC3339)
+		}
+		for _, name := range item.Categories {
+			e.Categories = append(e.Categories, AtomCategory{Term: name})
+		}
+		if item.Description != "" {
+			e.Content = &AtomContent{Type: "html", Value: item.Description}
+		}
+		if item.PubDate.After(updated) {
+			updated = item.PubDate.Time
+		}
+		a.Entries = append(a.Entries, e)
+	}
+	if updated.IsZero() {
+		updated = time.Now().In(FEEDTZ)
+	}
+	a.Updated = updated.Format(time.RFC3339)
+	return a
+}
+
+// JSONFeed is the JSON Feed 1.1 rendering of a channel
+type JSONFeed struct {
+	Version     string          `json:"version"`
+	Title       string          `json:"title"`
+	HomePageURL string          `json:"home_page_url,omitempty"`
+	FeedURL     string          `json:"feed_url,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Icon        string          `json:"icon,omitempty"`
+	Language    string          `json:"language,omitempty"`
+	Hubs        []JSONFeedHub   `json:"hubs,omitempty"`
+	Items       []*JSONFeedItem `json:"items"`
+}
+
+type JSONFeedHub struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type JSONFeedItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url,omitempty"`
+	Title         string   `json:"title"`
+	ContentHTML   string   `json:"content_html,omitempty"`
+	Image         string   `json:"image,omitempty"`
+	DatePublished string   `json:"date_published,omitempty"`
+	DateModified  string   `json:"date_modified,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+func jsonFeed(feed *RSS, self string) *JSONFeed {
+	c := feed.Channel
+	j := &JSONFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       c.Title,
+		HomePageURL: c.Link,
+		FeedURL:     self,
+		Description: c.Description,
+		Language:    c.Language,
+		Items:       []*JSONFeedItem{},
+	}
+	if c.Image != nil {
+		j.Icon = c.Image.URL
+	}
+	for _, l := range c.AtomLinks {
+		if l.Rel == "hub" {
+			j.Hubs = append(j.Hubs, JSONFeedHub{Type: "WebSub", URL: l.Href})
+		}
+	}
+
+	for _, item := range c.Items {
+		ji := &JSONFeedItem{
+			ID:           itemID(item),
+			URL:          item.Link,
+			Title:        item.Title,
+			ContentHTML:  item.Description,
+			Image:        rewriteImageURL(item.Cover),
+			DateModified: item.PubDate.Format(time.RFC3339),
+			Tags:         item.Categories,
+		}
+		if !item.Created.IsZero() {
+			ji.DatePublished = item.Created.Format(time.RFC3339)
+		}
+		if strings.HasPrefix(ji.Image, "data:") {
+			ji.Image = ""
+		}
+		j.Items = append(j.Items, ji)
+	}
+	return j
+}
+
+// itemID is the guid of an item, or its link for items without one
+func itemID(item *Item) string {
+	if item.GUID != nil {
+		return item.GUID.Value
+	}
+	if item.Link != "" {
+		return item.Link
+	}
+	return fmt.Sprintf("f95-%d", item.GameID)
+}
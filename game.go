@@ -0,0 +1,174 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GameDetail is the JSON representation of a stored game
+type GameDetail struct {
+	ID       int       `json:"id"`
+	Title    string    `json:"title"`
+	Version  string    `json:"version"`
+	Creator  string    `json:"creator"`
+	Rating   float64   `json:"rating"`
+	Overview string    `json:"overview,omitempty"`
+	Link     string    `json:"link"`
+	Cover    string    `json:"cover,omitempty"`
+	Tags     []int     `json:"tags"`
+	Prefixes []int     `json:"prefixes"`
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+
+	LastUpdate *time.Time `json:"last_update"` // latest version change, null without history
+}
+
+// fetchGameDetail loads a game with its creator, latest cover, tags and
+// prefixes. It returns sql.ErrNoRows when the game is unknown.
+func fetchGameDetail(db *sql.DB, id int) (*GameDetail, error) {
+	query := `
+		select g.id, g.title, coalesce(g.version, ''), coalesce(c.name, ''), coalesce(g.rating, 0), coalesce(g.overview, ''), g.created, g.updated
+		from game g
+		left join creator c on c.id = g.creator_id
+		where g.id = ?;
+	`
+
+	var g GameDetail
+	var created, updated string
+	err := db.QueryRow(query, id).Scan(&g.ID, &g.Title, &g.Version, &g.Creator, &g.Rating, &g.Overview, &created, &updated)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.Created, err = parseDBTime(created); err != nil {
+		return nil, err
+	}
+	if g.Updated, err = parseDBTime(updated); err != nil {
+		return nil, err
+	}
+	g.Created = g.Created.In(FEEDTZ)
+	g.Updated = g.Updated.In(FEEDTZ)
+	g.Link = fmt.Sprintf("https://f95zone.to/threads/%d", g.ID)
+
+	var recorded sql.NullString
+	err = db.QueryRow("select max(recorded) from version_history where game_id = ?;", id).Scan(&recorded)
+	if err != nil {
+		return nil, err
+	}
+	lastUpdate, err := parseLastUpdate(recorded)
+	if err != nil {
+		return nil, err
+	}
+	if !lastUpdate.IsZero() {
+		g.LastUpdate = &lastUpdate
+	}
+
+	coverQuery := `
+		select h.base || c.path from cover c
+		join host h on h.id = c.host_id
+		where c.game_id = ? order by c.id desc limit 1;
+	`
+	err = db.QueryRow(coverQuery, id).Scan(&g.Cover)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if g.Tags, err = queryIDs(db, "select tag_id from tags where game_id = ? order by tag_id;", id); err != nil {
+		return nil, err
+	}
+	if g.Prefixes, err = queryIDs(db, "select prefix_id from prefixes where game_id = ? order by prefix_id;", id); err != nil {
+		return nil, err
+	}
+
+	return &g, nil
+}
+
+// fetchPreviews returns the screenshot URLs of a game in insertion order
+func fetchPreviews(db *sql.DB, id int) ([]string, error) {
+	rows, err := db.Query(`
+		select h.base || p.path from preview p
+		join host h on h.id = p.host_id
+		where p.game_id = ? order by p.id;
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, rows.Err()
+}
+
+// Serve one stored game as JSON
+func serveGame(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil || id <= 0 {
+			writeError(w, "Invalid game id", http.StatusBadRequest)
+			return
+		}
+
+		game, err := fetchGameDetail(db, id)
+		if err == sql.ErrNoRows {
+			writeError(w, "Game not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			writeError(w, "Error loading game", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, game)
+	}
+}
+
+// Serve a random game, optionally limited to a tag, as JSON or a redirect
+func serveRandom(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := "select id from game order by random() limit 1;"
+		var args []any
+		if tag := r.URL.Query().Get("tag"); tag != "" {
+			query = `
+				select g.id from game g
+				join tags t on t.game_id = g.id
+				where t.tag_id = ?
+				order by random() limit 1;
+			`
+			args = append(args, tag)
+		}
+
+		var id int
+		err := db.QueryRow(query, args...).Scan(&id)
+		if err == sql.ErrNoRows {
+			writeError(w, "No games found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			writeError(w, "Error picking a game", http.StatusInternalServerError)
+			return
+		}
+
+		if r.URL.Query().Get("redirect") == "1" {
+			http.Redirect(w, r, fmt.Sprintf("https://f95zone.to/threads/%d", id), http.StatusFound)
+			return
+		}
+
+		game, err := fetchGameDetail(db, id)
+		if err != nil {
+			writeError(w, "Error loading game", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, game)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+)
+
+// GUIDMODE picks how items are identified, which decides what readers do
+// when a game changes:
+//
+//   - thread: the thread URL. The item never changes identity, so readers
+//     that track guids update it in place, or ignore the change entirely,
+//     instead of showing a new item.
+//   - version: the thread id and version. Every new version shows as a new
+//     item, while edits within a version update the existing one.
+//   - hash: a hash of the API data. Any change, including a rating or tag
+//     edit, shows as a new item.
+var GUIDMODE string
+
+var guidModes = map[string]bool{"thread": true, "version": true, "hash": true}
+
+// itemGUID identifies a game item according to GUIDMODE. Games ingested
+// before hashes were stored fall back to their version guid.
+func itemGUID(id int, link, version, hash string) *GUID {
+	switch GUIDMODE {
+	case "thread":
+		return &GUID{Value: link, IsPermaLink: "true"}
+	case "hash":
+		if len(hash) >= 16 {
+			return &GUID{Value: fmt.Sprintf("f95-%d-%s", id, hash[:16]), IsPermaLink: "false"}
+		}
+	}
+	return versionGUID(id, version)
+}
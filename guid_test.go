@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"testing"
+)
+
+func TestItemGUID(t *testing.T) {
+	const link = "https://f95zone.to/threads/1"
+	const hash = "0123456789abcdef0123"
+
+	tests := []struct {
+		mode, version, hash string
+		want                GUID
+	}{
+		{"thread", "0.1", hash, GUID{Value: link, IsPermaLink: "true"}},
+		{"version", "0.1", hash, GUID{Value: "f95-1-0.1", IsPermaLink: "false"}},
+		{"version", "", hash, GUID{Value: "f95-1", IsPermaLink: "false"}},
+		{"hash", "0.1", hash, GUID{Value: "f95-1-0123456789abcdef", IsPermaLink: "false"}},
+		{"hash", "0.1", "", GUID{Value: "f95-1-0.1", IsPermaLink: "false"}},
+	}
+
+	defer func(mode string) { GUIDMODE = mode }(GUIDMODE)
+	for _, tt := range tests {
+		GUIDMODE = tt.mode
+		if got := itemGUID(1, link, tt.version, tt.hash); *got != tt.want {
+			t.Errorf("%s mode, version %q, hash %q: got %+v, want %+v", tt.mode, tt.version, tt.hash, *got, tt.want)
+		}
+	}
+}
+
+// TestGUIDChangesWithVersion ingests a game twice and checks that a new
+// version gets a new guid in the version and hash modes while the link
+// stays the thread URL
+func TestGUIDChangesWithVersion(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	defer func(mode string) { GUIDMODE = mode }(GUIDMODE)
+
+	for _, tt := range []struct {
+		mode    string
+		changes bool
+	}{
+		{"thread", false},
+		{"version", true},
+		{"hash", true},
+	} {
+		t.Run(tt.mode, func(t *testing.T) {
+			GUIDMODE = tt.mode
+			db := newTestDB(t)
+			f := testListings(1)[0]
+
+			fetch := func() *Item {
+				ingestGame(db, f, gameHash(f))
+				items, err := fetchDataFromDB(context.Background(), db, []int{f.ThreadID}, FeedOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(items) != 1 {
+					t.Fatalf("got %d items, want 1", len(items))
+				}
+				return items[0]
+			}
+
+			before := fetch()
+			f.Version = "0.2"
+			after := fetch()
+
+			if before.Link != "https://f95zone.to/threads/1" || after.Link != before.Link {
+				t.Errorf("link changed from %q to %q", before.Link, after.Link)
+			}
+			if changed := *after.GUID != *before.GUID; changed != tt.changes {
+				t.Errorf("guid went from %q to %q, want changed %v", before.GUID.Value, after.GUID.Value, tt.changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"slices"
+)
+
+// gameHash fingerprints every field of a listing that is stored, so an
+// unchanged game can skip its inserts. Tags and prefixes are compared as
+// sets.
+func gameHash(f F95DATA) string {
+	f.Tags = slices.Sorted(slices.Values(f.Tags))
+	f.Prefixes = slices.Sorted(slices.Values(f.Prefixes))
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		log.Fatalf("failed to hash game %d: %v", f.ThreadID, err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fetchHashes returns the stored fingerprint of every game that has one
+func fetchHashes(db *sql.DB) (map[int]string, error) {
+	rows, err := db.Query("select id, hash from game where hash is not null;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := map[int]string{}
+	for rows.Next() {
+		var id int
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			return nil, err
+		}
+		hashes[id] = hash
+	}
+	return hashes, rows.Err()
+}
+
+func storeHash(db *sql.DB, gameID int, hash string) {
+	if _, err := db.Exec("update game set hash = ? where id = ?;", hash, gameID); err != nil {
+		log.Fatalf("failed to store game hash: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"testing"
+)
+
+func TestGameHash(t *testing.T) {
+	f := F95DATA{ThreadID: 1, Title: "Game", Version: "0.1", Tags: []int{3, 1, 2}, Prefixes: []int{7, 5}}
+	base := gameHash(f)
+
+	reordered := f
+	reordered.Tags = []int{2, 3, 1}
+	reordered.Prefixes = []int{5, 7}
+	if got := gameHash(reordered); got != base {
+		t.Errorf("reordered tags and prefixes changed the hash")
+	}
+	if f.Tags[0] != 3 {
+		t.Errorf("gameHash sorted the caller's tags: %v", f.Tags)
+	}
+
+	changed := f
+	changed.Version = "0.2"
+	if got := gameHash(changed); got == base {
+		t.Errorf("a new version kept the hash")
+	}
+}
+
+// testListings returns n listings as the API sends them, with a cover,
+// three screenshots and eight tags each
+func testListings(n int) []F95DATA {
+	listings := make([]F95DATA, n)
+	for i := range listings {
+		id := i + 1
+		f := F95DATA{
+			ThreadID: id,
+			Title:    fmt.Sprintf("Game %d", id),
+			Creator:  fmt.Sprintf("Creator %d", id%20),
+			Version:  "0.1",
+			Rating:   4,
+			Cover:    fmt.Sprintf("https://attachments.f95zone.to/2024/01/%d_cover.jpg", id),
+			Overview: "An overview.",
+			Prefixes: []int{7},
+		}
+		for j := 0; j < 3; j++ {
+			f.Screens = append(f.Screens, fmt.Sprintf("https://attachments.f95zone.to/2024/01/%d_%d.jpg", id, j))
+		}
+		for j := 0; j < 8; j++ {
+			f.Tags = append(f.Tags, (id+j*13)%200)
+		}
+		cleanData(&f)
+		listings[i] = f
+	}
+	return listings
+}
+
+// BenchmarkIngestUnchanged re-ingests 100 unchanged listings, skipping
+// them by their stored hash and ingesting every one
+func BenchmarkIngestUnchanged(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	db := newTestDB(b)
+	listings := testListings(100)
+	for _, f := range listings {
+		ingestGame(db, f, gameHash(f))
+	}
+
+	b.Run("hash skip", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			hashes, err := fetchHashes(db)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, f := range listings {
+				hash := gameHash(f)
+				if hashes[f.ThreadID] == hash {
+					continue
+				}
+				ingestGame(db, f, hash)
+			}
+		}
+	})
+	b.Run("no hash skip", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, f := range listings {
+				ingestGame(db, f, gameHash(f))
+			}
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var FEEDHEADERS string // extra feed response headers as Name: value pairs separated by ;
+
+// reservedHeaders are set by writeFeed itself and can't be configured
+var reservedHeaders = []string{"Content-Type", "Content-Length", "Content-Encoding", "Etag", "Last-Modified"}
+
+// feedHeaders parses FEEDHEADERS. Without a configured Cache-Control, one
+// allowing caches to keep the feed for the TTL is added.
+func feedHeaders() (http.Header, error) {
+	h := http.Header{}
+	for _, pair := range strings.Split(FEEDHEADERS, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, ":")
+		name, value = http.CanonicalHeaderKey(strings.TrimSpace(name)), strings.TrimSpace(value)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("F95_RSS_FEED_HEADERS: expected Name: value, got %q", pair)
+		}
+		for _, r := range reservedHeaders {
+			if name == r {
+				return nil, fmt.Errorf("F95_RSS_FEED_HEADERS: %s is set by the server", name)
+			}
+		}
+		h.Add(name, value)
+	}
+
+	if h.Get("Cache-Control") == "" {
+		if ttl := feedTTL(); ttl > 0 {
+			h.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", ttl*60))
+		}
+	}
+	return h, nil
+}
+
+// setFeedHeaders adds the configured headers to a feed response;
+// validateConfig rejects invalid ones
+func setFeedHeaders(w http.ResponseWriter) {
+	h, _ := feedHeaders()
+	for name, values := range h {
+		for _, v := range values {
+			w.Header().Add(name, v)
+		}
+	}
+}
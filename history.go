@@ -0,0 +1,28 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// recordVersion appends a version to the history of a game
+func recordVersion(db *sql.DB, gameID int, version string) {
+	_, err := db.Exec("insert into version_history (game_id, version) values (?, ?);", gameID, version)
+	if err != nil {
+		log.Fatalf("failed to record version history: %v", err)
+	}
+}
+
+// parseLastUpdate reads the latest history timestamp of a game, which is
+// NULL for games ingested before histories were kept
+func parseLastUpdate(recorded sql.NullString) (time.Time, error) {
+	if !recorded.Valid {
+		return time.Time{}, nil
+	}
+	t, err := parseDBTime(recorded.String)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(FEEDTZ), nil
+}
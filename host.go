@@ -0,0 +1,41 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// splitURL separates the scheme and host of a URL from its path, so that the
+// shared CDN prefix is stored once in the host table
+func splitURL(u string) (base, path string) {
+	i := strings.Index(u, "://")
+	if i < 0 {
+		return "", u
+	}
+
+	j := strings.Index(u[i+3:], "/")
+	if j < 0 {
+		return u, ""
+	}
+
+	return u[:i+3+j], u[i+3+j:]
+}
+
+// upsertHost returns the id of a host base, inserting it when new. It
+// takes two statements since the SQLCipher build's SQLite predates
+// RETURNING.
+func upsertHost(q queryer, base string) (int, error) {
+	if _, err := q.Exec("insert into host (base) values (?) on conflict (base) do nothing;", base); err != nil {
+		return 0, err
+	}
+
+	var id int
+	err := q.QueryRow("select id from host where base = ?;", base).Scan(&id)
+	return id, err
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var IMGCACHE int // resized images kept in memory, 0 to disable
+
+const (
+	imgMaxBytes = 16 << 20 // largest image proxied
+	imgMaxWidth = 4096     // largest ?w= accepted
+)
+
+// proxiedImage is an image body with its media type
+type proxiedImage struct {
+	contentType string
+	body        []byte
+}
+
+// imgCache holds resized images, evicting the oldest first
+var imgCache = struct {
+	sync.Mutex
+	entries map[string]*proxiedImage
+	order   []string
+}{entries: map[string]*proxiedImage{}}
+
+func cachedImage(key string) *proxiedImage {
+	imgCache.Lock()
+	defer imgCache.Unlock()
+	return imgCache.entries[key]
+}
+
+func cacheImage(key string, img *proxiedImage) {
+	if IMGCACHE <= 0 {
+		return
+	}
+
+	imgCache.Lock()
+	defer imgCache.Unlock()
+	if _, ok := imgCache.entries[key]; ok {
+		return
+	}
+	for len(imgCache.order) >= IMGCACHE {
+		delete(imgCache.entries, imgCache.order[0])
+		imgCache.order = imgCache.order[1:]
+	}
+	imgCache.entries[key] = img
+	imgCache.order = append(imgCache.order, key)
+}
+
+// fetchImage downloads an image of at most maxBytes through proxyClient
+func fetchImage(u string, maxBytes int) (*proxiedImage, error) {
+	resp, err := proxyClient.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "image/") {
+		return nil, fmt.Errorf("not an image: %q", resp.Header.Get("Content-Type"))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBytes)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxBytes {
+		return nil, fmt.Errorf("image larger than %d bytes", maxBytes)
+	}
+
+	return &proxiedImage{contentType: mediaType, body: body}, nil
+}
+
+// errNoResize means the image is already narrow enough
+var errNoResize = errors.New("image not wider than requested")
+
+// resizeImage scales an image down to width w, keeping its aspect ratio.
+// JPEGs stay JPEGs, everything else is re-encoded as PNG.
+func resizeImage(body []byte, w int) (*proxiedImage, error) {
+	src, format, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	b := src.Bounds()
+	if b.Dx() <= w {
+		return nil, errNoResize
+	}
+	h := max(1, b.Dy()*w/b.Dx())
+
+	dst := downscale(src, w, h)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+		return &proxiedImage{contentType: "image/jpeg", body: buf.Bytes()}, err
+	}
+	err = png.Encode(&buf, dst)
+	return &proxiedImage{contentType: "image/png", body: buf.Bytes()}, err
+}
+
+// downscale shrinks src to w x h by averaging the source pixels covered by
+// each destination pixel
+func downscale(src image.Image, w, h int) *image.RGBA64 {
+	b := src.Bounds()
+	dst := image.NewRGBA64(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/w)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// serveImage proxies a cover or preview: /img?url=<image url>&w=<width>.
+// Images are only ever scaled down; anything that can't be decoded is
+// passed through unchanged. Only IMGHOSTS are proxied.
+func serveImage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u := r.URL.Query().Get("url")
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Host == "" {
+			http.Error(w, "Invalid url", http.StatusBadRequest)
+			return
+		}
+		if !allowedImageHost(parsed) {
+			http.Error(w, "Image host not allowed", http.StatusForbidden)
+			return
+		}
+
+		width := 0
+		if v := r.URL.Query().Get("w"); v != "" {
+			width, err = strconv.Atoi(v)
+			if err != nil || width <= 0 || width > imgMaxWidth {
+				http.Error(w, "Invalid w", http.StatusBadRequest)
+				return
+			}
+		}
+
+		key := strconv.Itoa(width) + " " + u
+		img := cachedImage(key)
+		if img == nil {
+			orig, err := fetchImage(u, imgMaxBytes)
+			if errors.Is(err, errPrivateAddress) {
+				log.Printf("Image proxy refused %s: %v", u, err)
+				http.Error(w, "Image host not allowed", http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				log.Printf("Image proxy failed for %s: %v", u, err)
+				http.Error(w, "Error fetching image", http.StatusBadGateway)
+				return
+			}
+
+			img = orig
+			if width > 0 {
+				resized, err := resizeImage(orig.body, width)
+				switch {
+				case err == nil:
+					img = resized
+					cacheImage(key, img)
+				case err != errNoResize:
+					log.Printf("Serving %s unresized: %v", u, err)
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", img.contentType)
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Write(img.body)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// IMGHOSTS are the hosts the image proxy fetches from, each with its
+// subdomains
+var IMGHOSTS []string
+
+// errPrivateAddress rejects proxy connections to internal networks
+var errPrivateAddress = errors.New("address is not public")
+
+// proxyClient fetches proxied images. It refuses to connect to private,
+// loopback and link-local addresses whatever name resolved to them, and to
+// follow redirects off the allowed hosts.
+var proxyClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		// No proxy: the address checked must be the image server's
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				addr, err := netip.ParseAddrPort(address)
+				if err != nil {
+					return err
+				}
+				if !publicAddr(addr.Addr()) {
+					return fmt.Errorf("%s: %w", address, errPrivateAddress)
+				}
+				return nil
+			},
+		}).DialContext,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		if !allowedImageHost(req.URL) {
+			return fmt.Errorf("redirect to %s is not allowed", req.URL.Host)
+		}
+		return nil
+	},
+}
+
+// allowedImageHost reports whether u is an http(s) URL on one of IMGHOSTS
+func allowedImageHost(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	for _, allowed := range IMGHOSTS {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// publicAddr reports whether addr is routable on the internet
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	// Carrier-grade NAT is shared address space, not the internet
+	return !netip.MustParsePrefix("100.64.0.0/10").Contains(addr)
+}
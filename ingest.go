@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	ALLOWTAGS     string // comma separated tag ids or names to ingest
+	ALLOWPREFIXES string // comma separated prefix ids or names to ingest
+	ALLOWFILE     string // file of tag:<id|name> and prefix:<id|name> lines
+
+	MINTHREADID   int  // games with a lower thread id are not ingested
+	SKIPNOVERSION bool // don't ingest games without a version
+	MINSCREENS    int  // games with fewer screenshots are not ingested
+)
+
+// allowlist restricts ingestion to games carrying any of its tags or prefixes
+type allowlist struct {
+	tags     map[int]bool
+	prefixes map[int]bool
+}
+
+// loadAllowlist resolves the configured allowlist against the tag and prefix
+// name tables. It returns nil when no allowlist is configured.
+func loadAllowlist(db *sql.DB) (*allowlist, error) {
+	var tags, prefixes []string
+	tags = append(tags, splitList([]string{ALLOWTAGS})...)
+	prefixes = append(prefixes, splitList([]string{ALLOWPREFIXES})...)
+
+	if ALLOWFILE != "" {
+		t, p, err := readAllowFile(ALLOWFILE)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, t...)
+		prefixes = append(prefixes, p...)
+	}
+
+	if len(tags) == 0 && len(prefixes) == 0 {
+		return nil, nil
+	}
+
+	a := &allowlist{tags: map[int]bool{}, prefixes: map[int]bool{}}
+	for _, t := range tags {
+		id, err := resolveName(db, "tag", t)
+		if err != nil {
+			return nil, err
+		}
+		a.tags[id] = true
+	}
+	for _, p := range prefixes {
+		id, err := resolveName(db, "prefix", p)
+		if err != nil {
+			return nil, err
+		}
+		a.prefixes[id] = true
+	}
+
+	return a, nil
+}
+
+// allows reports whether a game matches the allowlist
+func (a *allowlist) allows(f F95DATA) bool {
+	if a == nil {
+		return true
+	}
+	for _, id := range f.Tags {
+		if a.tags[id] {
+			return true
+		}
+	}
+	for _, id := range f.Prefixes {
+		if a.prefixes[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// skipReason says why a listing is not ingested
+type skipReason int
+
+const (
+	notSkipped skipReason = iota
+	skipTooOld
+	skipNotAllowed
+	skipNoVersion
+	skipFewScreens
+)
+
+func (r skipReason) String() string {
+	switch r {
+	case skipTooOld:
+		return fmt.Sprintf("its thread id is below %d", MINTHREADID)
+	case skipNotAllowed:
+		return "it is outside the allowlist"
+	case skipNoVersion:
+		return "it has no version"
+	case skipFewScreens:
+		return fmt.Sprintf("it has fewer than %d screenshots", MINSCREENS)
+	}
+	return "it is not skipped"
+}
+
+// filterGame cleans a listing and applies the ingest filters to it. Every
+// path that stores games goes through it, so they skip the same ones.
+// Merged tags are replaced first, so the allowlist, the hash and the tag
+// diff all see the tags as stored.
+func filterGame(allow *allowlist, aliases tagAliases, f *F95DATA) skipReason {
+	if f.ThreadID < MINTHREADID {
+		return skipTooOld
+	}
+	f.Tags = aliases.apply(f.Tags)
+	if !allow.allows(*f) {
+		return skipNotAllowed
+	}
+
+	cleanData(f)
+	if SKIPNOVERSION && f.Version == "" {
+		return skipNoVersion
+	}
+	if len(f.Screens) < MINSCREENS {
+		return skipFewScreens
+	}
+	return notSkipped
+}
+
+// readAllowFile parses tag:<id|name> and prefix:<id|name> lines, ignoring
+// blank lines and # comments
+func readAllowFile(path string) (tags, prefixes []string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		kind, value, ok := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+		switch {
+		case ok && kind == "tag" && value != "":
+			tags = append(tags, value)
+		case ok && kind == "prefix" && value != "":
+			prefixes = append(prefixes, value)
+		default:
+			return nil, nil, fmt.Errorf("%s:%d: expected tag:<id|name> or prefix:<id|name>", path, n)
+		}
+	}
+
+	return tags, prefixes, scanner.Err()
+}
+
+// resolveName turns an id or a case-insensitive name from the tag or prefix
+// table into an id
+func resolveName(db *sql.DB, table, value string) (int, error) {
+	if id, err := strconv.Atoi(value); err == nil {
+		return id, nil
+	}
+
+	var id int
+	err := db.QueryRow("select id from "+table+" where lower(name) = lower(?);", value).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("unknown %s %q", table, value)
+	}
+	return id, err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+)
+
+// Concurrent cover downloads when inlining
+const inlineWorkers = 4
+
+var INLINEMAX int // largest cover embedded as a data: URI, in bytes
+
+// inlineCovers replaces each item's cover URL by a data: URI. Covers that
+// can't be fetched, aren't images, exceed INLINEMAX or aren't on IMGHOSTS
+// keep their URL.
+func inlineCovers(items []*Item) {
+	sem := make(chan struct{}, inlineWorkers)
+	var wg sync.WaitGroup
+
+	for _, item := range items {
+		if item.Cover == "" {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(item *Item) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			uri, err := dataURI(item.Cover)
+			if err != nil {
+				log.Printf("Keeping cover URL for %d: %v", item.GameID, err)
+				return
+			}
+			item.Cover = uri
+			item.Thumb = "" // the embedded cover needs no download
+		}(item)
+	}
+
+	wg.Wait()
+}
+
+// dataURI encodes an image as a base64 data: URI. It is fetched like the
+// image proxy does, and kept in the image cache under the key of the
+// unresized image so the next feed doesn't download it again.
+func dataURI(u string) (string, error) {
+	parsed, err := url.Parse(u)
+	if err != nil || !allowedImageHost(parsed) {
+		return "", fmt.Errorf("image host of %q not allowed", u)
+	}
+
+	key := "0 " + u
+	img := cachedImage(key)
+	if img == nil {
+		if img, err = fetchImage(u, INLINEMAX); err != nil {
+			return "", err
+		}
+		cacheImage(key, img)
+	}
+	if len(img.body) > INLINEMAX {
+		return "", fmt.Errorf("image larger than %d bytes", INLINEMAX)
+	}
+
+	return "data:" + img.contentType + ";base64," + base64.StdEncoding.EncodeToString(img.body), nil
+}
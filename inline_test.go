@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestDataURI checks inlined covers go through the image proxy's host
+// allowlist and address guard, and come from the image cache when they can
+func TestDataURI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	}))
+	defer srv.Close()
+
+	oldHosts := IMGHOSTS
+	defer func() { IMGHOSTS = oldHosts }()
+
+	IMGHOSTS = []string{"f95zone.to"}
+	if _, err := dataURI(srv.URL + "/cover.png"); err == nil {
+		t.Error("cover off IMGHOSTS was inlined")
+	}
+
+	IMGHOSTS = []string{"127.0.0.1"}
+	if _, err := dataURI(srv.URL + "/cover.png"); !errors.Is(err, errPrivateAddress) {
+		t.Errorf("loopback cover: err = %v, want errPrivateAddress", err)
+	}
+
+	IMGHOSTS = []string{"f95zone.to"}
+	u := "https://attachments.f95zone.to/cover.png"
+	cacheImage("0 "+u, &proxiedImage{contentType: "image/png", body: []byte("png")})
+	got, err := dataURI(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "data:image/png;base64,cG5n"; got != want {
+		t.Errorf("dataURI = %q, want %q", got, want)
+	}
+}
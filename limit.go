@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	MAXGENERATIONS    int           // feeds built concurrently, 0 for no limit
+	GENERATIONWAIT    time.Duration // how long a request queues for a slot
+	MAXITEMS          int           // games in one feed response, 0 for no limit
+	GENERATIONTIMEOUT time.Duration // how long one feed may take to build, 0 for no limit
+
+	generations chan struct{}
+)
+
+// limitGenerations bounds how many feeds are built at once. Requests queue
+// for up to GENERATIONWAIT and are then turned away with 503.
+func limitGenerations(next http.HandlerFunc) http.HandlerFunc {
+	next = timeoutGeneration(next)
+	if MAXGENERATIONS <= 0 {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		timer := time.NewTimer(GENERATIONWAIT)
+		defer timer.Stop()
+
+		select {
+		case generations <- struct{}{}:
+			defer func() { <-generations }()
+			next(w, r)
+		case <-timer.C:
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, int(GENERATIONWAIT.Seconds()))))
+			http.Error(w, "Too many feed requests, try again later", http.StatusServiceUnavailable)
+		case <-r.Context().Done():
+		}
+	}
+}
+
+// timeoutGeneration cancels the request context after GENERATIONTIMEOUT and
+// logs generations that used most of it, so the limit can be tuned
+func timeoutGeneration(next http.HandlerFunc) http.HandlerFunc {
+	if GENERATIONTIMEOUT <= 0 {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), GENERATIONTIMEOUT)
+		defer cancel()
+
+		start := time.Now()
+		next(w, r.WithContext(ctx))
+		if elapsed := time.Since(start); elapsed > GENERATIONTIMEOUT*8/10 {
+			log.Printf("Slow feed generation for %s: %v of %v", r.URL.RequestURI(), elapsed.Round(time.Millisecond), GENERATIONTIMEOUT)
+		}
+	}
+}
+
+// feedError reports a failed generation, with 503 when it ran out of time
+func feedError(w http.ResponseWriter, err error, msg string) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, int(GENERATIONTIMEOUT.Seconds()))))
+		http.Error(w, "Feed generation timed out, try again later", http.StatusServiceUnavailable)
+		return
+	}
+	http.Error(w, msg, http.StatusInternalServerError)
+}
+
+// capItems keeps the first MAXITEMS ids or items of a feed, announcing a
+// cut in the X-Feed-Truncated header as "<kept> of <total>"
+func capItems[T any](w http.ResponseWriter, ids []T) []T {
+	if MAXITEMS <= 0 || len(ids) <= MAXITEMS {
+		return ids
+	}
+	w.Header().Set("X-Feed-Truncated", strconv.Itoa(MAXITEMS)+" of "+strconv.Itoa(len(ids)))
+	return ids[:MAXITEMS]
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// LISTS are watchlists kept besides F95_RSS_ID_FILE, merged by /feed/all
+var LISTS string // comma separated name=path pairs
+
+// mainListName labels the games of F95_RSS_ID_FILE
+const mainListName = "watchlist"
+
+// watchlist is an id file with the label its games get in /feed/all
+type watchlist struct {
+	name string
+	path string
+}
+
+// watchlists returns the id file followed by the lists of F95_RSS_LISTS
+func watchlists() ([]watchlist, error) {
+	lists := []watchlist{{mainListName, IDFILE}}
+	seen := map[string]bool{mainListName: true}
+	for _, pair := range splitList([]string{LISTS}) {
+		name, path, ok := strings.Cut(pair, "=")
+		name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+		if !ok || name == "" || path == "" {
+			return nil, fmt.Errorf("F95_RSS_LISTS: expected name=path, got %q", pair)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("F95_RSS_LISTS: duplicate list %q", name)
+		}
+		seen[name] = true
+		lists = append(lists, watchlist{name, path})
+	}
+	return lists, nil
+}
+
+// Serve every watchlist in one feed. Games are listed once, with a category
+// for each list they are on, pinned games first and then by update date.
+func serveAllFeed(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lists, err := watchlists()
+		if err != nil {
+			http.Error(w, "Invalid list configuration", http.StatusInternalServerError)
+			return
+		}
+
+		var ids []int
+		labels := map[int][]string{}
+		for _, l := range lists {
+			listIDs, err := readIDsFromFile(l.path)
+			if err != nil {
+				http.Error(w, "Error reading list "+l.name, http.StatusInternalServerError)
+				return
+			}
+			for _, id := range listIDs {
+				if _, ok := labels[id]; !ok {
+					ids = append(ids, id)
+				}
+				if n := len(labels[id]); n == 0 || labels[id][n-1] != l.name {
+					labels[id] = append(labels[id], l.name)
+				}
+			}
+		}
+		// The lists are merged by date, so the cap can only apply once every
+		// item is fetched and sorted
+		items, err := fetchDataFromDB(r.Context(), db, ids, parseFeedOptions(r))
+		if err != nil {
+			feedError(w, err, "Error generating feed")
+			return
+		}
+		for _, item := range items {
+			item.Categories = append(item.Categories, labels[item.GameID]...)
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].Pinned != items[j].Pinned {
+				return items[i].Pinned
+			}
+			return items[i].PubDate.After(items[j].PubDate.Time)
+		})
+		items = capItems(w, items)
+
+		feed := newFeed(&Channel{
+			Title:       "F95zone All Lists",
+			Link:        "https://f95zone.com/latest",
+			Description: "Latest updates of every watchlist",
+		}, items)
+		addHub(feed, topicURL("/feed/all"))
+		writeFeed(w, r, feed)
+	}
+}
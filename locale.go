@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// channelText is the localized title and description of the main feed
+type channelText struct {
+	Title       string
+	Description string
+}
+
+var channelTexts = map[string]channelText{
+	"en": {"F95zone Latest Updates", "F95zone Adult Games - Latest Updates RSS Feed"},
+	"de": {"F95zone Neueste Updates", "F95zone Erwachsenenspiele - RSS-Feed der neuesten Updates"},
+	"es": {"F95zone Últimas actualizaciones", "F95zone Juegos para adultos - Feed RSS de últimas actualizaciones"},
+	"fr": {"F95zone Dernières mises à jour", "F95zone Jeux pour adultes - Flux RSS des dernières mises à jour"},
+	"ja": {"F95zone 最新アップデート", "F95zone アダルトゲーム - 最新アップデート RSS フィード"},
+	"th": {"F95zone อัปเดตล่าสุด", "F95zone เกมสำหรับผู้ใหญ่ - ฟีด RSS อัปเดตล่าสุด"},
+}
+
+// preferredLanguage picks the best supported language from an
+// Accept-Language header, defaulting to English
+func preferredLanguage(r *http.Request) string {
+	type choice struct {
+		lang string
+		q    float64
+	}
+
+	var choices []choice
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if _, ok := channelTexts[base]; ok && q > 0 {
+			choices = append(choices, choice{base, q})
+		}
+	}
+
+	if len(choices) == 0 {
+		return "en"
+	}
+
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	return choices[0].lang
+}
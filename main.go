@@ -2,48 +2,136 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"database/sql"
-	"encoding/json"
+	"encoding/hex"
 	"encoding/xml"
+	"errors"
+	"flag"
 	"fmt"
+	"html"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/robfig/cron/v3"
-	_ "modernc.org/sqlite"
 )
 
-const BASE_API = "https://f95zone.to/sam/latest_alpha/latest_data.php?cmd=list&cat=games"
+const DEFAULT_API = "https://f95zone.to/sam/latest_alpha/latest_data.php?cmd=list&cat=games"
 
 var (
-	DBFILE  = os.Getenv("F95_RSS_DB")
-	IDFILE  = os.Getenv("F95_RSS_ID_FILE") // id.txt file
-	RSSCRON = os.Getenv("F95_RSS_CRON")
+	BASE_API string
+
+	DBDRIVER string // sqlite or postgres, which takes F95_RSS_DB as its DSN
+	DBFILE   string
+	DBKEY    string // SQLCipher key, needs a build with -tags sqlcipher
+	IDFILE   string // id.txt file
+	RSSCRON  string
+	APIQUERY string // extra API parameters, e.g. sort=date&rows=60
+
+	WEBSUBHUB string // WebSub hub to ping after updates
+	PUBLICURL string // externally reachable base URL, e.g. https://rss.example.com
+	IMAGEURL  string // channel logo shown by aggregators
+
+	SHOWCREATED bool           // mention the first-seen date in descriptions
+	FEEDTZ      *time.Location // timezone used to render feed dates
+
+	JITTER     time.Duration // random delay added to each scheduled update
+	OUTPUT     string        // static file the feed is written to after updates
+	OUTPUTGZIP bool          // also write OUTPUT.gz for pre-compressed serving
+	TTL        int           // minutes readers may cache the feed, 0 derives it from the cron
+
+	OVERVIEWMAX int // characters of overview shown, 0 for the full text
+	MAXDESC     int // characters of item description, 0 for no limit
+
+	ADDR     string // address the HTTP server listens on
+	BASEPATH string // path prefix when served behind a reverse proxy, e.g. /rss
 )
 
 // RSS feed structures for XML serialization
 type RSS struct {
 	XMLName xml.Name `xml:"rss"`
 	Version string   `xml:"version,attr"`
+	AtomNS  string   `xml:"xmlns:atom,attr,omitempty"`
+	SyNS    string   `xml:"xmlns:sy,attr,omitempty"`
 	Channel *Channel `xml:"channel"`
 }
 
 type Channel struct {
-	Title       string  `xml:"title"`
-	Link        string  `xml:"link"`
-	Description string  `xml:"description"`
-	Items       []*Item `xml:"item"`
+	Title       string      `xml:"title"`
+	Link        string      `xml:"link"`
+	Description string      `xml:"description"`
+	Language    string      `xml:"language,omitempty"`
+	TTL         int         `xml:"ttl,omitempty"`
+	SyPeriod    string      `xml:"sy:updatePeriod,omitempty"`
+	SyFrequency int         `xml:"sy:updateFrequency,omitempty"`
+	Image       *Image      `xml:"image,omitempty"`
+	AtomLinks   []*AtomLink `xml:"atom:link"`
+	Items       []*Item     `xml:"item"`
+}
+
+// Image is the channel logo; title and link must match the channel's
+type Image struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+// AtomLink is an <atom:link> element embedded in the RSS channel
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
 }
 
 type Item struct {
 	Title       string    `xml:"title"`
 	Link        string    `xml:"link"`
-	Description string    `xml:"description"`
-	PubDate     time.Time `xml:"pubDate"`
+	Description string    `xml:"description,omitempty"`
+	GUID        *GUID     `xml:"guid,omitempty"`
+	PubDate     RSSDate   `xml:"pubDate"`
+	Categories  []string  `xml:"category"`
+	Created     time.Time `xml:"-"` // when the game was first ingested
+	LastUpdate  time.Time `xml:"-"` // when its version last changed, zero without history
+
+	// Source data the description is rendered from
+	GameID   int    `xml:"-"`
+	Name     string `xml:"-"`
+	Version  string `xml:"-"`
+	Overview string `xml:"-"`
+	Cover    string `xml:"-"`
+	Thumb    string `xml:"-"` // cover thumbnail, "" to show the full cover
+	Screens  int    `xml:"-"`
+	Pinned   bool   `xml:"-"`
+
+	CreatorID int           `xml:"-"`
+	Creator   string        `xml:"-"`
+	Related   []RelatedGame `xml:"-"` // other games by the same creator
+
+	TagChanges []string `xml:"-"` // latest tag and prefix delta, as +Name and -Name
+}
+
+// RSSDate is a time rendered in the RFC 822 format RSS requires
+type RSSDate struct {
+	time.Time
+}
+
+func (d RSSDate) MarshalText() ([]byte, error) {
+	return []byte(d.Format(time.RFC1123Z)), nil
+}
+
+// GUID identifies an item independently of its link
+type GUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr,omitempty"`
 }
 
 type F95 struct {
@@ -60,36 +148,71 @@ type F95DATA struct {
 	Version  string `json:"version"`
 	// Views    int      `json:"views"`
 	// Likes    int      `json:"likes"`
-	Prefixes []int `json:"prefixes"`
-	Tags     []int `json:"tags"`
-	// Rating   int      `json:"rating"`
-	Cover   string   `json:"cover"`
-	Screens []string `json:"screens"`
+	Prefixes []int    `json:"prefixes"`
+	Tags     []int    `json:"tags"`
+	Rating   float64  `json:"rating"`
+	Cover    string   `json:"cover"`
+	Screens  []string `json:"screens"`
+	Overview string   `json:"overview"`
 	// Date     string   `json:"date"`
 	// Watched  bool     `json:"watched"`
 	// Ignored  bool     `json:"ignored"`
 	// New      bool     `json:"new"`
 }
 
-// Read IDs from a plain text file, one per line
+// missingIDFiles are the ID files found missing, so the warning isn't
+// repeated on every request
+var missingIDFiles sync.Map
+
+// Read IDs from a plain text file, one per line. A missing file is an empty
+// list.
 func readIDsFromFile(filePath string) ([]int, error) {
 	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		// Not created yet: an empty watchlist, warned about once
+		if _, warned := missingIDFiles.LoadOrStore(filePath, true); !warned {
+			log.Printf("ID file %s does not exist, serving an empty watchlist", filePath)
+		}
+		return []int{}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
+	missingIDFiles.Delete(filePath)
 
-	var ids []int
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" {
-			id, err := strconv.Atoi(line) // Convert the line to an integer ID
-			if err != nil {
-				return nil, err
-			}
-			ids = append(ids, id)
+	return parseIDs(file, filePath)
+}
+
+// parseIDs reads one thread id per line. Blank lines and # comments are
+// ignored, as are a leading byte order mark and CRLF line endings. Lines
+// that aren't a number are logged and skipped rather than failing the
+// whole list, but a zero or negative id is an error.
+func parseIDs(r io.Reader, name string) ([]int, error) {
+	ids := []int{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		id, err := strconv.Atoi(line) // Convert the line to an integer ID
+		if err != nil {
+			log.Printf("%s:%d: skipping invalid id %q", name, n, line)
+			continue
+		}
+		if id <= 0 {
+			return nil, fmt.Errorf("%s:%d: id %d is not positive", name, n, id)
 		}
+		ids = append(ids, id)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -100,19 +223,38 @@ func readIDsFromFile(filePath string) ([]int, error) {
 }
 
 // Function to fetch data from the database based on the list of IDs
-func fetchDataFromDB(db *sql.DB, ids []int) ([]*Item, error) {
+func fetchDataFromDB(ctx context.Context, db *sql.DB, ids []int, opts FeedOptions) ([]*Item, error) {
 	var items []*Item
 
 	// Loop through each ID and execute a query for each one
 	for _, id := range ids {
-		gameQuery := "SELECT id, title, version, updated FROM game WHERE id = ?"
-		game := db.QueryRow(gameQuery, id)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 
-		var gameID int
-		var title, version, updated string
+		gameQuery := `
+			SELECT game.id, title, version, coalesce(overview, ''), created, updated,
+				exists (select 1 from pinned p where p.game_id = game.id),
+				coalesce(creator_id, 0), coalesce(c.name, ''),
+				coalesce((
+					select p.prefix_id from prefixes p
+					where p.game_id = game.id and p.prefix_id in (?, ?, ?)
+					order by p.rowid desc limit 1
+				), 0),
+				(select max(v.recorded) from version_history v where v.game_id = game.id),
+				coalesce(game.hash, '')
+			FROM game LEFT JOIN creator c ON c.id = game.creator_id
+			WHERE game.id = ?
+		`
+		game := db.QueryRowContext(ctx, gameQuery, statusPrefixes["completed"], statusPrefixes["onhold"], statusPrefixes["abandoned"], id)
+
+		var gameID, creatorID, statusPrefix int
+		var title, version, overview, created, updated, creator, hash string
+		var pinned bool
+		var recorded sql.NullString
 
 		// Fetch data from the row
-		err := game.Scan(&gameID, &title, &version, &updated)
+		err := game.Scan(&gameID, &title, &version, &overview, &created, &updated, &pinned, &creatorID, &creator, &statusPrefix, &recorded, &hash)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				// If no rows are returned, skip this ID
@@ -122,56 +264,305 @@ func fetchDataFromDB(db *sql.DB, ids []int) ([]*Item, error) {
 		}
 
 		var coverURL string
-		coverQuery := "select url from cover where game_id = ? order by id desc limit 1;"
-		err = db.QueryRow(coverQuery, gameID).Scan(&coverURL)
-		if err != nil {
-			log.Fatalf("Failed to get the coverURL of id %d: %v", gameID, err)
+		var screens int
+		var tagChanges []string
+		if !opts.Compact {
+			coverQuery := `
+				select h.base || c.path from cover c
+				join host h on h.id = c.host_id
+				where c.game_id = ? order by c.id desc limit 1;
+			`
+			err = db.QueryRowContext(ctx, coverQuery, gameID).Scan(&coverURL)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			// A game without a cover is still listed, just without an image
+			if err != nil && err != sql.ErrNoRows {
+				return nil, fmt.Errorf("failed to get the cover of id %d: %w", gameID, err)
+			}
+
+			previewQuery := "select count(*) from preview where game_id = ?;"
+			err = db.QueryRowContext(ctx, previewQuery, gameID).Scan(&screens)
+			if err != nil {
+				return nil, err
+			}
+
+			if tagChanges, err = fetchTagChanges(ctx, db, gameID); err != nil {
+				return nil, err
+			}
+		}
+
+		var thumbURL string
+		if COVERTHUMBS {
+			thumbURL = coverThumb(coverURL)
 		}
 
 		link := fmt.Sprintf("https://f95zone.to/threads/%d", gameID)
 
-		t, err := time.Parse(time.RFC3339, updated)
+		t, err := parseDBTime(updated)
 		if err != nil {
-			log.Fatalf("Error parsing time: %v", err)
+			return nil, fmt.Errorf("error parsing the update time of id %d: %w", gameID, err)
+		}
+
+		c, err := parseDBTime(created)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing the creation time of id %d: %w", gameID, err)
+		}
+
+		lastUpdate, err := parseLastUpdate(recorded)
+		if err != nil {
+			return nil, err
 		}
 
 		// Create a feed item and add it to the list
 		item := &Item{
-			Title:       fmt.Sprintf("%s [%s]", title, version),
-			Link:        link,
-			Description: "<img src=\"" + coverURL + "\" alt=\"" + title + "\" />",
-			PubDate:     t.Local(),
+			Title: itemTitle(TitleFields{
+				Title:   title,
+				Version: version,
+				Creator: creator,
+				Status:  statusLabels[statusPrefix],
+			}),
+			Link:       link,
+			GUID:       itemGUID(gameID, link, version, hash),
+			PubDate:    RSSDate{t.In(FEEDTZ)},
+			Created:    c.In(FEEDTZ),
+			LastUpdate: lastUpdate,
+			GameID:     gameID,
+			Name:       title,
+			Version:    version,
+			Overview:   overview,
+			Cover:      coverURL,
+			Thumb:      thumbURL,
+			Screens:    screens,
+			Pinned:     pinned,
+			TagChanges: tagChanges,
+
+			CreatorID: creatorID,
+			Creator:   creator,
 		}
 		items = append(items, item)
 	}
+	pinFirst(items)
+
+	if opts.Unread {
+		var err error
+		if items, err = dropRead(db, items); err != nil {
+			return nil, err
+		}
+	}
+
+	// Compact feeds are titles and links only
+	if opts.Compact {
+		return items, nil
+	}
+
+	if opts.Inline {
+		inlineCovers(items)
+	}
+
+	if err := fetchRelated(ctx, db, items, RELATEDMAX); err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		item.Description = describe(item)
+	}
+
+	if opts.ScreenItems {
+		return withScreenItems(db, items)
+	}
 
 	return items, nil
 }
 
+// versionGUID identifies a release of a game, so readers show every new
+// version as a new item while the link stays the thread URL
+func versionGUID(id int, version string) *GUID {
+	value := fmt.Sprintf("f95-%d", id)
+	if version != "" {
+		value += "-" + version
+	}
+	return &GUID{Value: value, IsPermaLink: "false"}
+}
+
+// describe renders the HTML description of a feed item
+func describe(item *Item) string {
+	var blocks []string
+	if item.Overview != "" {
+		overview, cut := truncateText(item.Overview, OVERVIEWMAX)
+		block := "<p>" + strings.ReplaceAll(html.EscapeString(overview), "\n", "<br />")
+		if cut {
+			block += "&hellip; <a href=\"" + html.EscapeString(item.Link) + "\">Read more</a>"
+		}
+		blocks = append(blocks, block+"</p>")
+	}
+
+	if item.Cover != "" {
+		cover := rewriteImageURL(item.Cover)
+		img := "<img src=\"" + html.EscapeString(cover) + "\" alt=\"" + html.EscapeString(item.Name) + "\" />"
+		if item.Thumb != "" {
+			img = "<a href=\"" + html.EscapeString(cover) + "\"><img src=\"" + html.EscapeString(rewriteImageURL(item.Thumb)) + "\" alt=\"" + html.EscapeString(item.Name) + "\" /></a>"
+		}
+		blocks = append(blocks, img)
+	}
+	blocks = append(blocks, "<p>"+screenshotCount(item.Screens)+" &middot; <a href=\""+html.EscapeString(item.Link)+"\">View thread</a></p>")
+	if len(item.TagChanges) > 0 {
+		blocks = append(blocks, "<p>Changes: "+html.EscapeString(strings.Join(item.TagChanges, ", "))+"</p>")
+	}
+	if len(item.Related) > 0 {
+		var links []string
+		for _, g := range item.Related {
+			links = append(links, "<a href=\""+html.EscapeString(g.Link)+"\">"+html.EscapeString(g.Title)+"</a>")
+		}
+		blocks = append(blocks, "<p>More from "+html.EscapeString(item.Creator)+": "+strings.Join(links, ", ")+"</p>")
+	}
+	if SHOWCREATED {
+		block := "<p>First seen: " + item.Created.Format("2006-01-02")
+		if !item.LastUpdate.IsZero() {
+			block += " &middot; Updated: " + item.LastUpdate.Format("2006-01-02")
+		}
+		blocks = append(blocks, block+"</p>")
+	}
+	return capDescription(blocks, item.Link, MAXDESC)
+}
+
+// capDescription joins the HTML blocks of a description, keeping at most n
+// characters. Whole blocks are dropped from the end so no tag is cut, and a
+// link to the thread is appended instead. n <= 0 keeps every block.
+func capDescription(blocks []string, link string, n int) string {
+	description := strings.Join(blocks, "")
+	if n <= 0 || utf8.RuneCountInString(description) <= n {
+		return description
+	}
+
+	more := "<p>&hellip; <a href=\"" + html.EscapeString(link) + "\">View thread</a></p>"
+	size := utf8.RuneCountInString(more)
+
+	description = ""
+	for _, block := range blocks {
+		size += utf8.RuneCountInString(block)
+		if size > n {
+			break
+		}
+		description += block
+	}
+	return description + more
+}
+
+// truncateText shortens s to at most n characters, preferring to cut at a
+// word boundary. n <= 0 keeps the whole text.
+func truncateText(s string, n int) (string, bool) {
+	r := []rune(s)
+	if n <= 0 || len(r) <= n {
+		return s, false
+	}
+
+	cut := string(r[:n])
+	if i := strings.LastIndexAny(cut, " \n\t"); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimSpace(cut), true
+}
+
+// screenshotCount renders a human readable number of screenshots
+func screenshotCount(n int) string {
+	switch n {
+	case 0:
+		return "no screenshots"
+	case 1:
+		return "1 screenshot"
+	default:
+		return strconv.Itoa(n) + " screenshots"
+	}
+}
+
 // Generate RSS feed with selected IDs
-func generateFeed(db *sql.DB, ids []int) (*RSS, error) {
+func generateFeed(ctx context.Context, db *sql.DB, ids []int, opts FeedOptions) (*RSS, error) {
+	text, ok := channelTexts[opts.Lang]
+	if !ok {
+		opts.Lang = "en"
+		text = channelTexts["en"]
+	}
+
 	channel := &Channel{
-		Title:       "F95zone Latest Updates",
+		Title:       text.Title,
 		Link:        "https://f95zone.com/latest",
-		Description: "F95zone Adult Games - Latest Updates RSS Feed",
+		Description: text.Description,
+		Language:    opts.Lang,
 	}
 
-	items, err := fetchDataFromDB(db, ids)
+	items, err := fetchDataFromDB(ctx, db, ids, opts)
 	if err != nil {
 		return nil, err
 	}
 
+	if opts.Summary {
+		summary, err := summaryItem(ctx, db, items)
+		if err != nil {
+			return nil, err
+		}
+		if summary != nil {
+			items = append([]*Item{summary}, items...)
+		}
+	}
+
+	// Outages would otherwise go unnoticed behind the stored data
+	stale, err := staleItem(db)
+	if err != nil {
+		return nil, err
+	}
+	if stale != nil {
+		items = append([]*Item{stale}, items...)
+	}
+
+	feed := newFeed(channel, items)
+
+	// Advertise the hub so WebSub-capable readers can subscribe for pushes
+	addHub(feed, feedURL())
+
+	return feed, nil
+}
+
+// newFeed wraps a channel and its items into an RSS 2.0 document
+func newFeed(channel *Channel, items []*Item) *RSS {
+	if IMAGEURL != "" {
+		channel.Image = &Image{
+			URL:   IMAGEURL,
+			Title: channel.Title,
+			Link:  channel.Link,
+		}
+	}
+
 	channel.Items = items
 
-	return &RSS{
+	feed := &RSS{
 		Version: "2.0",
 		Channel: channel,
-	}, nil
+	}
+
+	// Hint readers to poll at the rate the data actually changes
+	if ttl := feedTTL(); ttl > 0 {
+		channel.TTL = ttl
+		feed.SyNS = "http://purl.org/rss/1.0/modules/syndication/"
+		if ttl < 60 {
+			channel.SyPeriod, channel.SyFrequency = "hourly", 60/ttl
+		} else {
+			channel.SyPeriod, channel.SyFrequency = "daily", max(1, 1440/ttl)
+		}
+	}
+
+	return feed
 }
 
 // Serve RSS feed
 func serveFeed(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		// Read IDs from file
 		ids, err := readIDsFromFile(IDFILE)
 		if err != nil {
@@ -179,169 +570,497 @@ func serveFeed(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		feed, err := generateFeed(db, ids)
+		ids, err = filterIDs(db, filter, ids)
+		if err != nil {
+			http.Error(w, "Error filtering games", http.StatusInternalServerError)
+			return
+		}
+		ids, err = paginate(w, r, ids)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ids = capItems(w, ids)
+
+		// Skip building the feed when nothing changed since the client's copy
+		modified, err := feedModified(db, ids)
+		if err != nil {
+			http.Error(w, "Error checking for updates", http.StatusInternalServerError)
+			return
+		}
+		stale, err := staleItem(db)
 		if err != nil {
-			http.Error(w, "Error generating feed", http.StatusInternalServerError)
+			http.Error(w, "Error checking for updates", http.StatusInternalServerError)
+			return
+		}
+		if stale != nil && stale.PubDate.After(modified) {
+			modified = stale.PubDate.Time
+		}
+		if notModified(r, modified) {
+			setFeedHeaders(w)
+			w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusNotModified)
 			return
 		}
 
-		// Marshal the RSS feed into XML
-		rssXML, err := xml.MarshalIndent(feed, "", "  ")
+		feed, err := generateFeed(r.Context(), db, ids, parseFeedOptions(r))
 		if err != nil {
-			http.Error(w, "Error converting feed to XML", http.StatusInternalServerError)
+			feedError(w, err, "Error generating feed")
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/xml")
-		w.Write(rssXML)
+		// The channel texts are localized
+		w.Header().Add("Vary", "Accept-Language")
+
+		writeFeed(w, r, feed)
 	}
 }
 
-func getData() (data F95) {
-	req, err := http.Get(BASE_API)
+// writeFeed marshals the feed in the format the request asks for, see
+// feedFormat, and writes it to the response. HEAD requests and conditional
+// requests are answered by http.ServeContent using the body's ETag and the
+// newest item date, or the last pin, read or block change when it is newer.
+func writeFeed(w http.ResponseWriter, r *http.Request, feed *RSS) {
+	self := requestURL(r)
+	addSelfLink(feed, self)
+
+	format, negotiated := feedFormat(r)
+	if negotiated {
+		w.Header().Add("Vary", "Accept")
+	}
+	body, contentType, err := marshalFeed(feed, format, self)
 	if err != nil {
-		log.Fatalf("Failed to create table: %v", err)
+		http.Error(w, "Error converting feed to "+format, http.StatusInternalServerError)
+		return
 	}
 
-	defer req.Body.Close()
+	setFeedHeaders(w)
+	sum := sha256.Sum256(body)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Content-Type", contentType)
 
-	if err = json.NewDecoder(req.Body).Decode(&data); err != nil {
-		log.Fatalf("Failed to create table: %v", err)
+	modified := lastModified(feed.Channel.Items)
+	if changed := feedStateTime(); changed.After(modified) {
+		modified = changed
 	}
+	http.ServeContent(w, r, "", modified, bytes.NewReader(body))
+}
 
-	return data
+// lastModified returns the newest item date, or the zero time for no items
+func lastModified(items []*Item) time.Time {
+	var latest time.Time
+	for _, item := range items {
+		if item.PubDate.After(latest) {
+			latest = item.PubDate.Time
+		}
+	}
+	return latest
 }
 
-func updateDatabase(db *sql.DB) {
-	data := getData()
+// errNotModified reports that the API answered 304 to a conditional request
+var errNotModified = errors.New("API data not modified")
+
+// getData fetches the latest list. The validators of the last response are
+// sent along, so an unchanged list costs the server a 304; servers that
+// ignore them just answer in full.
+func getData(db *sql.DB) (F95, error) {
+	if APIFILE != "" {
+		return readDataFile(APIFILE)
+	}
+
+	u, err := apiURL()
+	if err != nil {
+		return F95{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return F95{}, err
+	}
+	if getMeta(db, "api_url") == u {
+		if etag := getMeta(db, "api_etag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := getMeta(db, "api_last_modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return F95{}, fmt.Errorf("failed to fetch API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return F95{}, errNotModified
+	}
+
+	var body io.Reader = resp.Body
+	var limited *io.LimitedReader
+	if APIMAXBYTES > 0 {
+		// Read one byte past the cap to tell a full body from an oversized one
+		limited = &io.LimitedReader{R: resp.Body, N: int64(APIMAXBYTES) + 1}
+		body = limited
+	}
+
+	data, err := decodeData(body)
+	if limited != nil && limited.N == 0 {
+		return F95{}, fmt.Errorf("API response exceeds %d bytes", APIMAXBYTES)
+	}
+	if err == errTruncated {
+		// The entries read so far are still ingested, but the validators
+		// would turn the next fetch of the full list into a 304
+		return data, nil
+	}
+	if err != nil {
+		return data, err
+	}
+
+	setMeta(db, "api_url", u)
+	setMeta(db, "api_etag", resp.Header.Get("ETag"))
+	setMeta(db, "api_last_modified", resp.Header.Get("Last-Modified"))
+	return data, nil
+}
+
+// updateDatabase ingests the latest API data and returns the IDs of games
+// that are new or whose version changed
+func updateDatabase(db *sql.DB) ([]int, error) {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+	ensureDatabase(db)
+
+	var changed []int
+	seen := make(map[int]bool)
+
+	run := &UpdateRun{Started: time.Now()}
+	defer recordRun(db, run)
+
+	data, err := getData(db)
+	if err == errNotModified {
+		log.Println("API data unchanged, skipping update")
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Update failed: %v", err)
+		run.Error = err.Error()
+		return nil, err
+	}
+
+	allow, err := loadAllowlist(db)
+	if err != nil {
+		log.Printf("Update failed: invalid allowlist: %v", err)
+		run.Error = err.Error()
+		return nil, err
+	}
+	aliases, err := loadTagAliases(db)
+	if err != nil {
+		log.Printf("Update failed: %v", err)
+		run.Error = err.Error()
+		return nil, err
+	}
+
+	hashes, err := fetchHashes(db)
+	if err != nil {
+		log.Printf("Update failed: %v", err)
+		run.Error = err.Error()
+		return nil, err
+	}
+	queued, err := queryIDs(db, "select game_id from refetch;")
+	if err != nil {
+		log.Printf("Update failed: %v", err)
+		run.Error = err.Error()
+		return nil, err
+	}
+	refetch := make(map[int]bool, len(queued))
+	for _, id := range queued {
+		refetch[id] = true
+	}
+
+	skipped, tooOld, noVersion, fewScreens, unchanged := 0, 0, 0, 0, 0
 	for _, f := range data.Msg.Data {
-		creatorID := insertCreator(db, f.Creator)
-		insertGame(db, f.ThreadID, f.Title, f.Version, creatorID)
-		insertCover(db, f.ThreadID, f.Cover)
-		insertPreview(db, f.ThreadID, f.Screens)
-		insertTags(db, f.ThreadID, f.Tags)
-		insertPrefixes(db, f.ThreadID, f.Prefixes)
+		switch filterGame(allow, aliases, &f) {
+		case skipTooOld:
+			tooOld++
+			continue
+		case skipNotAllowed:
+			skipped++
+			continue
+		case skipNoVersion:
+			noVersion++
+			continue
+		case skipFewScreens:
+			fewScreens++
+			continue
+		}
+		seen[f.ThreadID] = true
+
+		// Most of a listing is unchanged between polls
+		hash := gameHash(f)
+		if hashes[f.ThreadID] == hash && !refetch[f.ThreadID] {
+			unchanged++
+			continue
+		}
+
+		updated, warning := ingestGame(db, f, hash)
+		if updated {
+			changed = append(changed, f.ThreadID)
+		}
+		if warning != "" {
+			run.Warnings = append(run.Warnings, warning)
+		}
+	}
+	takeRefetched(db, seen)
+	if skipped > 0 {
+		log.Printf("Skipped %d games outside the allowlist", skipped)
+	}
+	if tooOld > 0 {
+		log.Printf("Skipped %d games below thread id %d", tooOld, MINTHREADID)
+	}
+	if noVersion > 0 {
+		log.Printf("Skipped %d games without a version", noVersion)
+	}
+	if fewScreens > 0 {
+		log.Printf("Skipped %d games with fewer than %d screenshots", fewScreens, MINSCREENS)
+	}
+	if unchanged > 0 {
+		log.Printf("Skipped %d unchanged games", unchanged)
 	}
 	log.Println("Update successfully")
+
+	run.Processed = len(data.Msg.Data) - skipped - tooOld - noVersion - fewScreens
+	run.Changed = len(changed)
+
+	return changed, nil
+}
+
+// cleanData normalizes the text fields of a listing before it is stored
+func cleanData(f *F95DATA) {
+	f.Title = sanitizeText(f.Title)
+	f.Creator = normalizeCreator(sanitizeText(f.Creator))
+	f.Version = normalizeVersion(sanitizeText(f.Version))
+	f.Overview = sanitizeText(f.Overview)
 }
 
+// ingestGame stores a cleaned listing with its cover, previews, tags and
+// prefixes, and reports as insertGame does
+func ingestGame(db *sql.DB, f F95DATA, hash string) (bool, string) {
+	var known bool
+	if err := db.QueryRow("select exists (select 1 from game where id = ?);", f.ThreadID).Scan(&known); err != nil {
+		log.Fatalf("failed to look up game %d: %v", f.ThreadID, err)
+	}
+
+	recordStatusChanges(db, f.ThreadID, f.Prefixes)
+	creatorID := insertCreator(db, f.Creator)
+	updated, warning := insertGame(db, f.ThreadID, f.Title, f.Version, f.Rating, f.Overview, creatorID)
+	if known {
+		recordTagChanges(db, f.ThreadID, f.Tags, f.Prefixes)
+	}
+	insertCover(db, f.ThreadID, f.Cover)
+	insertPreview(db, f.ThreadID, f.Screens)
+	insertTags(db, f.ThreadID, f.Tags)
+	insertPrefixes(db, f.ThreadID, f.Prefixes)
+	storeHash(db, f.ThreadID, hash)
+	return updated, warning
+}
+
+// insertCreator returns the id of a creator, inserting it when new. Like
+// upsertHost it avoids RETURNING for the SQLCipher build.
 func insertCreator(db *sql.DB, creator string) int {
-	var id int
+	key := creatorKey(creator)
 	query := `
-		INSERT INTO creator (name)
-		VALUES (?)
-		ON CONFLICT (name) DO UPDATE SET name = name
-		RETURNING id;
+		INSERT INTO creator (name, normalized)
+		VALUES (?, ?)
+		ON CONFLICT (normalized) DO NOTHING;
 	`
+	if _, err := db.Exec(query, creator, key); err != nil {
+		log.Fatalf("failed to insert creator: %v", err)
+	}
 
-	err := db.QueryRow(query, creator).Scan(&id)
-	if err != nil {
+	var id int
+	if err := db.QueryRow("SELECT id FROM creator WHERE normalized = ?;", key).Scan(&id); err != nil {
 		log.Fatalf("failed to insert creator: %v", err)
 	}
 
 	return id
 }
 
-func insertGame(db *sql.DB, id int, title string, version string, creatorId int) {
+// insertGame upserts a game and reports whether it is new or its version
+// changed. A version that sorts below the stored one is kept out unless
+// downgrades are allowed, and is described in the returned warning.
+func insertGame(db *sql.DB, id int, title string, version string, rating float64, overview string, creatorId int) (bool, string) {
+	var oldVersion sql.NullString
+	err := db.QueryRow("select version from game where id = ?", id).Scan(&oldVersion)
+	if err != nil && err != sql.ErrNoRows {
+		log.Fatalf("failed to get game version: %v", err)
+	}
+	changed := err == sql.ErrNoRows || oldVersion.String != version
+
+	var warning string
+	if changed && oldVersion.Valid && compareVersions(version, oldVersion.String) < 0 {
+		warning = fmt.Sprintf("game %d: version went from %q to %q", id, oldVersion.String, version)
+		if !ALLOWDOWNGRADE {
+			warning += ", keeping " + strconv.Quote(oldVersion.String)
+			version, changed = oldVersion.String, false
+		}
+		log.Printf("Suspicious downgrade of %s", warning)
+	}
+
 	query := `
 		insert into game (
-			id, title, version, creator_id
-		) values (?, ?, ?, ?)
+			id, title, version, rating, overview, creator_id
+		) values (?, ?, ?, ?, ?, ?)
 		on conflict (id) do update set
 			title = excluded.title,
 			version = excluded.version,
+			rating = excluded.rating,
+			overview = coalesce(nullif(excluded.overview, ''), game.overview),
 			creator_id = excluded.creator_id
 		;
 	`
 
-	_, err := db.Exec(query, id, title, version, creatorId)
+	_, err = db.Exec(query, id, title, version, rating, overview, creatorId)
 	if err != nil {
 		log.Fatalf("failed to insert game: %v", err)
 	}
+	if changed {
+		recordVersion(db, id, version)
+	}
+
+	return changed, warning
 }
 
 func insertCover(db *sql.DB, gameID int, coverURL string) {
-	query := `insert or ignore into cover (url, game_id) values (?, ?);`
+	query := `insert into cover (host_id, path, game_id) values (?, ?, ?) on conflict do nothing;`
+
+	base, path := splitURL(coverURL)
+	hostID, err := upsertHost(db, base)
+	if err != nil {
+		log.Fatalf("failed to insert cover host: %v", err)
+	}
 
-	_, err := db.Exec(query, coverURL, gameID)
+	_, err = db.Exec(query, hostID, path, gameID)
 	if err != nil {
 		log.Fatalf("failed to insert cover: %v", err)
 	}
 }
 
 func insertPreview(db *sql.DB, gameID int, previewURL []string) {
-	query := `insert or ignore into preview (url, game_id) values (?, ?);`
-
+	// Screenshots nearly always share a host
+	hosts := map[string]int{}
+	var rows [][]any
 	for _, s := range previewURL {
-		_, err := db.Exec(query, s, gameID)
-		if err != nil {
-			log.Fatalf("failed to insert preview: %v", err)
+		base, path := splitURL(s)
+		hostID, ok := hosts[base]
+		if !ok {
+			var err error
+			hostID, err = upsertHost(db, base)
+			if err != nil {
+				log.Fatalf("failed to insert preview host: %v", err)
+			}
+			hosts[base] = hostID
 		}
+		rows = append(rows, []any{hostID, path, gameID})
+	}
+
+	err := insertRowsIgnore(db, `insert into preview (host_id, path, game_id) values`, rows)
+	if err != nil {
+		log.Fatalf("failed to insert preview: %v", err)
 	}
 }
 
 func insertTags(db *sql.DB, gameID int, Tags []int) {
-	query := `insert or ignore into tags (game_id, tag_id) values (?, ?);`
-
+	rows := make([][]any, 0, len(Tags))
 	for _, s := range Tags {
-		_, err := db.Exec(query, gameID, s)
-		if err != nil {
-			log.Fatalf("failed to insert Tags: %v", err)
-		}
+		rows = append(rows, []any{gameID, s})
+	}
+
+	err := insertRowsIgnore(db, `insert into tags (game_id, tag_id) values`, rows)
+	if err != nil {
+		log.Fatalf("failed to insert Tags: %v", err)
+	}
+	if err := deleteMissing(db, "tags", "tag_id", gameID, Tags); err != nil {
+		log.Fatalf("failed to delete removed Tags: %v", err)
 	}
 }
 
 func insertPrefixes(db *sql.DB, gameID int, Prefixes []int) {
-	query := `insert or ignore into prefixes (game_id, prefix_id) values (?, ?);`
-
+	rows := make([][]any, 0, len(Prefixes))
 	for _, s := range Prefixes {
-		_, err := db.Exec(query, gameID, s)
-		if err != nil {
-			log.Fatalf("failed to insert Prefixes: %v", err)
-		}
+		rows = append(rows, []any{gameID, s})
 	}
-}
 
-func createDatabase(dbFile string) {
-	file, err := os.Create(dbFile)
+	err := insertRowsIgnore(db, `insert into prefixes (game_id, prefix_id) values`, rows)
 	if err != nil {
-		log.Fatalf("Failed to create database file: %v", err)
+		log.Fatalf("failed to insert Prefixes: %v", err)
+	}
+	if err := deleteMissing(db, "prefixes", "prefix_id", gameID, Prefixes); err != nil {
+		log.Fatalf("failed to delete removed Prefixes: %v", err)
 	}
-	file.Close()
+}
 
-	db, err := sql.Open("sqlite", dbFile)
+// createDatabase creates the schema in a single transaction. Every statement
+// is idempotent, so instances starting together against a new file are safe:
+// the first one creates the tables and the others find them.
+func createDatabase(db *sql.DB) {
+	tx, err := db.Begin()
 	if err != nil {
-		log.Fatalf("Failed to open the new database: %v", err)
+		log.Fatalf("Failed to begin schema creation: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := createTables(tx); err != nil {
+		log.Fatalf("Failed to create table: %v", err)
 	}
-	defer db.Close()
 
-	_, err = db.Exec(`
+	if err := tx.Commit(); err != nil {
+		log.Fatalf("Failed to create tables: %v", err)
+	}
+}
+
+// createTables creates the base schema; migrateDatabase adds the rest
+func createTables(tx *sql.Tx) error {
+	_, err := tx.Exec(sqlDialect.schema(`
 		create table if not exists creator (
 			id integer primary key AUTOINCREMENT,
-			name text not null unique
+			name text not null unique,
+			normalized text
 		);
 
 		create table if not exists game (
 			id integer primary key,
 			title text not null,
 			version text,
+			rating real,
+			overview text,
 			created timestamp default (datetime(current_timestamp, 'localtime')),
 			updated timestamp default (datetime(current_timestamp, 'localtime')),
 			creator_id integer,
 			foreign key(creator_id) references creator(id)
 		);
 
+		create table if not exists host (
+			id integer primary key autoincrement,
+			base text not null unique
+		);
+
 		create table if not exists cover (
 			id integer primary key autoincrement,
-			url text not null unique,
+			host_id integer not null,
+			path text not null,
 			game_id integer,
+			unique(host_id, path),
+			foreign key(host_id) references host(id),
 			foreign key(game_id) references game(id)
 		);
 
 		create table if not exists preview (
 			id integer primary key autoincrement,
-			url text not null unique,
+			host_id integer not null,
+			path text not null,
 			game_id integer,
+			unique(host_id, path),
+			foreign key(host_id) references host(id),
 			foreign key(game_id) references game(id)
 		);
 
@@ -358,61 +1077,196 @@ func createDatabase(dbFile string) {
 			PRIMARY KEY(game_id, prefix_id),
 			foreign key(game_id) references game(id)
 		);
+	`))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(sqlDialect.extraSchema())
+	return err
+}
 
-		create trigger if not exists update_timestamp
-		after update on game
-		for each row
-		begin
-			update game
-			set updated = current_timestamp
-			where id = old.id;
-		end;
-	`)
+// runUpdate ingests the API, regenerates the feed file and announces the
+// changes. Failures are logged and the first one is returned.
+func runUpdate(db *sql.DB) error {
+	changed, updateErr := updateDatabase(db)
+	resetTagCounts()
+	ids, err := readIDsFromFile(IDFILE) // Read IDs from file every 30 minutes
 	if err != nil {
-		log.Fatalf("Failed to create table: %v", err)
+		log.Fatalf("Error reading IDs: %v", err)
 	}
+	err = publishFeed(db, ids, changed)
 
-	log.Println("Database and tables created successfully.")
+	if updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+// publishFeed regenerates the feed after an ingest: it snapshots the feed,
+// writes F95_RSS_OUTPUT, publishes when any of changed is in ids, pings the
+// hub for every feed the changes show in, and sends the notifications
+func publishFeed(db *sql.DB, ids, changed []int) error {
+	feed, err := generateFeed(context.Background(), db, ids, FeedOptions{})
+	if err != nil {
+		log.Println("Error generating feed:", err)
+	} else {
+		snapshotFeed(db, feed)
+		if OUTPUT != "" {
+			if err = writeFeedFile(OUTPUT, feed); err != nil {
+				log.Println("Error writing feed file:", err)
+			}
+		}
+	}
+	if containsAny(ids, changed) {
+		publishUpdates(db, ids, changed)
+	}
+	for _, topic := range hubTopics(db, ids, changed) {
+		pingHub(topic)
+	}
+	// Also runs without changes to send what quiet hours held back
+	notifyUpdates(db, ids, changed)
+	return err
 }
 
 func main() {
-	// Check if the database file exists
-	if _, err := os.Stat(DBFILE); err != nil {
-		if os.IsNotExist(err) {
+	flag.Parse()
+	if err := loadConfig(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := validateConfig(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	logConfig()
+
+	if err := validateAPIURL(); err != nil {
+		log.Fatalf("Invalid API configuration: %v", err)
+	}
+
+	// A new Postgres database looks no different from an emptied one
+	var existed bool
+	if DBDRIVER == "sqlite" {
+		_, err := os.Stat(DBFILE)
+		existed = !os.IsNotExist(err)
+		if !existed {
 			log.Println("Database file does not exist, creating it...")
-			createDatabase(DBFILE)
-		} else {
-			log.Fatalf("Error checking database file: %v", err)
 		}
-	} else {
-		log.Println("Database file already exists.")
 	}
 
-	db, err := sql.Open("sqlite", DBFILE)
+	// The SQLite driver creates the file on first use
+	driverName, dsn, err := dbSource()
+	if err != nil {
+		log.Fatalf("Failed to open the database: %v", err)
+	}
+	db, err := sql.Open(driverName, dsn)
 	if err != nil {
 		log.Fatalf("Failed to open the database: %v", err)
 	}
 	defer db.Close()
 
-	// Start HTTP server to serve the feed
-	http.HandleFunc("/feed", serveFeed(db))
+	// An encrypted file only reads as a database with the right key
+	if err := checkDatabase(db); err != nil {
+		if DBDRIVER == "postgres" {
+			log.Fatalf("Failed to connect to the database: %v", err)
+		}
+		if DBKEY == "" {
+			log.Fatalf("Failed to read the database, set F95_RSS_DB_KEY if it is encrypted: %v", err)
+		}
+		log.Fatalf("Failed to read the database, check F95_RSS_DB_KEY: %v", err)
+	}
 
-	c := cron.New()
+	// A file left empty, e.g. by a fresh volume mounted over the path, opens
+	// fine but has no tables
+	if missing, err := missingTables(db); err == nil && existed && len(missing) > 0 {
+		log.Printf("Database is missing tables %s, creating the schema", strings.Join(missing, ", "))
+	}
+	if DBDRIVER == "postgres" {
+		checkSessionZone(db)
+	}
+	createDatabase(db)
+	migrateDatabase(db)
+	ensureDatabase(db)
+	if err := loadFeedState(db); err != nil {
+		log.Printf("Ignoring the stored feed state time: %v", err)
+	}
 
-	c.AddFunc(RSSCRON, func() {
-		updateDatabase(db)
-		ids, err := readIDsFromFile(IDFILE) // Read IDs from file every 30 minutes
-		if err != nil {
-			log.Fatalf("Error reading IDs: %v", err)
+	// Scheduled by an external cron instead of the one below
+	if *runOnce {
+		if OUTPUT == "" {
+			log.Println("F95_RSS_OUTPUT is not set, no feed file will be written")
 		}
-		_, err = generateFeed(db, ids)
-		if err != nil {
-			log.Println("Error generating feed:", err)
+		if err := runUpdate(db); err != nil {
+			db.Close()
+			os.Exit(1) // runUpdate logged the failure
 		}
-	})
+		return
+	}
+
+	go watchDatabase(db)
+	go watchQuietHours(db)
+
+	// Start HTTP server to serve the feed
+	http.HandleFunc("GET /{$}", servePreview(db))
+	http.HandleFunc("/feed", limitGenerations(serveFeed(db)))
+	http.HandleFunc("/feed.rss", limitGenerations(serveFeed(db)))
+	http.HandleFunc("/feed.atom", limitGenerations(serveFeed(db)))
+	http.HandleFunc("/feed.json", limitGenerations(serveFeed(db)))
+	http.HandleFunc("/feed/new-creators", limitGenerations(serveNewCreators(db)))
+	http.HandleFunc("/feed/abandoned", limitGenerations(serveAbandoned(db)))
+	http.HandleFunc("/feed/recommended", limitGenerations(serveRecommended(db)))
+	http.HandleFunc("/feed/all", limitGenerations(serveAllFeed(db)))
+	http.HandleFunc("GET /admin/incomplete", requireAdmin(false, serveIncomplete(db, false)))
+	http.HandleFunc("POST /admin/incomplete", requireAdmin(true, serveIncomplete(db, true)))
+	http.HandleFunc("/admin/runs", requireAdmin(false, serveRuns(db)))
+	http.HandleFunc("/admin/validate", requireAdmin(false, serveValidate(db)))
+	http.HandleFunc("POST /admin/rebuild", requireAdmin(true, serveRebuild(db)))
+	http.HandleFunc("GET /admin/api/{id}", requireAdmin(false, serveRawAPI))
+	http.HandleFunc("GET /admin/game/{id}/raw", requireAdmin(false, serveRawGame(db)))
+	http.HandleFunc("GET /admin/diff", requireAdmin(false, serveDiff(db)))
+	http.HandleFunc("POST /admin/refresh/{id}", requireAdmin(true, serveRefresh(db)))
+	http.HandleFunc("POST /admin/tags/merge", requireAdmin(true, serveMergeTags(db)))
+	http.HandleFunc("PATCH /admin/tags/{id}", requireAdmin(true, serveRenameTag(db)))
+	http.HandleFunc("GET /game/{id}", serveGame(db))
+	http.HandleFunc("GET /random", serveRandom(db))
+	http.HandleFunc("GET /tags", serveTags(db))
+	http.HandleFunc("GET /creators", serveCreators(db))
+	http.HandleFunc("GET /img", serveImage())
+	http.HandleFunc("GET /events", serveEvents)
+	http.HandleFunc("POST /read/{guid...}", requireAdmin(true, serveMarkRead(db, true)))
+	http.HandleFunc("DELETE /read/{guid...}", requireAdmin(true, serveMarkRead(db, false)))
+	http.HandleFunc("DELETE /read", requireAdmin(true, serveClearRead(db)))
+	http.HandleFunc("POST /ids/{id}/pin", requireAdmin(true, servePin(db, true)))
+	http.HandleFunc("POST /ids/{id}/unpin", requireAdmin(true, servePin(db, false)))
+	http.HandleFunc("POST /block/{id}", requireAdmin(true, serveBlock(db, true)))
+	http.HandleFunc("DELETE /block/{id}", requireAdmin(true, serveBlock(db, false)))
+	http.HandleFunc("GET /export.csv", serveExportCSV(db))
+	http.HandleFunc("POST /feeds", requireAdmin(true, createSavedFeed(db)))
+	http.HandleFunc("GET /feed/saved/{name}", limitGenerations(serveSavedFeed(db)))
+
+	c := cron.New()
+
+	var updateID cron.EntryID
+	if updatesDisabled() {
+		log.Println("F95_RSS_CRON is off, scheduled updates are disabled")
+	} else {
+		updateID, _ = c.AddFunc(RSSCRON, func() {
+			waitJitter()
+			runUpdate(db)
+		})
+	}
+
+	// Tag and prefix names change rarely
+	if METAURL != "" {
+		c.AddFunc(METACRON, func() { refreshMetadata(db) })
+		go refreshMetadata(db)
+		log.Printf("Refreshing metadata on %q", METACRON)
+	}
+
+	c.AddFunc(PRUNECRON, func() { pruneDatabase(db) })
+	log.Printf("Pruning the database on %q", PRUNECRON)
 
 	c.Start()
+	logNextRun(c, updateID)
 
-	log.Println("Serving feed on http://localhost:8080/feed")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	log.Printf("Serving feed on %s%s/feed", ADDR, BASEPATH)
+	log.Fatal(http.ListenAndServe(ADDR, logRequests(withBasePath(http.DefaultServeMux))))
 }
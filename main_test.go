@@ -0,0 +1,182 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain loads the settings from the environment and the defaults, as
+// main does without -config
+func TestMain(m *testing.M) {
+	if err := loadConfig(); err != nil {
+		log.Fatal(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestDB opens a fresh database file with the full schema, pointing
+// DBFILE at it for the duration of the test
+func newTestDB(tb testing.TB) *sql.DB {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "f95.db")
+	old := DBFILE
+	DBFILE = path
+	tb.Cleanup(func() { DBFILE = old })
+
+	dsn, err := dbDSN(path, "")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	db, err := sql.Open(dbDriver, dsn)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	createDatabase(db)
+	migrateDatabase(db)
+	return db
+}
+
+// seedGames bulk inserts n games with ids 1 to n, spread over 100
+// creators, a day of updates each and tagsPerGame of 200 tags
+func seedGames(tb testing.TB, db *sql.DB, n, tagsPerGame int) []int {
+	tb.Helper()
+	tx, err := db.Begin()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	var creators, games, tags [][]any
+	for i := 1; i <= 100; i++ {
+		name := fmt.Sprintf("Creator %d", i)
+		creators = append(creators, []any{i, name, normalizeCreator(name)})
+	}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	ids := make([]int, n)
+	for i := 1; i <= n; i++ {
+		ids[i-1] = i
+		updated := start.AddDate(0, 0, i%365).Format(sqliteTime)
+		games = append(games, []any{i, fmt.Sprintf("Game %d", i), fmt.Sprintf("0.%d", i%10), float64(i%50) / 10, 1 + i%100, updated, updated})
+		for j := 0; j < tagsPerGame; j++ {
+			tags = append(tags, []any{i, (i*7 + j*31) % 200})
+		}
+	}
+	for _, batch := range []struct {
+		insert string
+		rows   [][]any
+	}{
+		{"insert into creator (id, name, normalized) values", creators},
+		{"insert into game (id, title, version, rating, creator_id, created, updated) values", games},
+		{"insert or ignore into tags (game_id, tag_id) values", tags},
+	} {
+		if err := insertRows(tx, batch.insert, batch.rows); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		tb.Fatal(err)
+	}
+	return ids
+}
+
+// writeIDFile points IDFILE at a file listing ids
+func writeIDFile(tb testing.TB, ids []int) {
+	tb.Helper()
+	var b strings.Builder
+	for _, id := range ids {
+		fmt.Fprintln(&b, id)
+	}
+	path := filepath.Join(tb.TempDir(), "ids.txt")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		tb.Fatal(err)
+	}
+	old := IDFILE
+	IDFILE = path
+	tb.Cleanup(func() { IDFILE = old })
+}
+
+func FuzzReadIDs(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"123\n456\n",
+		"\uFEFF123\r\n456\r\n",
+		"# watchlist\n123 # a game\n\n  456  \n",
+		"abc\n123\n",
+		"99999999999999999999999999\n",
+		"-5\n",
+		"0\n",
+		"\x00\xff\n",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ids, err := parseIDs(strings.NewReader(string(data)), "fuzz")
+		if err != nil {
+			if ids != nil {
+				t.Fatalf("got ids %v along with error %v", ids, err)
+			}
+			return
+		}
+		if ids == nil {
+			t.Fatal("got a nil slice without an error")
+		}
+		for _, id := range ids {
+			if id <= 0 {
+				t.Fatalf("got non-positive id %d", id)
+			}
+		}
+	})
+}
+
+func TestParseIDs(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	tests := []struct {
+		name    string
+		in      string
+		want    []int
+		wantErr bool
+	}{
+		{"plain", "1\n2\n3\n", []int{1, 2, 3}, false},
+		{"bom and crlf", "\uFEFF10\r\n20\r\n", []int{10, 20}, false},
+		{"comments and blanks", "# list\n\n5 # five\n  6  \n", []int{5, 6}, false},
+		{"bad lines skipped", "x\n7\n1e3\n", []int{7}, false},
+		{"overflow skipped", "99999999999999999999999\n8\n", []int{8}, false},
+		{"zero", "1\n0\n", nil, true},
+		{"negative", "-4\n", nil, true},
+		{"empty", "", []int{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseIDs(strings.NewReader(tt.in), "test")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
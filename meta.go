@@ -0,0 +1,24 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+// getMeta reads a value of the meta key-value table, "" when unset
+func getMeta(db *sql.DB, key string) string {
+	var value string
+	err := db.QueryRow("select value from meta where key = ?;", key).Scan(&value)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Failed to read meta %s: %v", key, err)
+	}
+	return value
+}
+
+// setMeta stores a value in the meta key-value table
+func setMeta(db *sql.DB, key, value string) {
+	_, err := db.Exec("insert into meta (key, value) values (?, ?) on conflict (key) do update set value = excluded.value;", key, value)
+	if err != nil {
+		log.Printf("Failed to write meta %s: %v", key, err)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+var (
+	METAURL  string // tag and prefix name mapping
+	METACRON string // schedule of the metadata refresh
+)
+
+// F95META is the tag and prefix name mapping published by f95zone's
+// latest updates page
+type F95META struct {
+	Tags     map[string]string `json:"tags"`
+	Prefixes map[string][]struct {
+		Name     string `json:"name"`
+		Prefixes []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"prefixes"`
+	} `json:"prefixes"`
+}
+
+func getMetadata() (meta F95META, err error) {
+	resp, err := apiClient.Get(METAURL)
+	if err != nil {
+		return meta, fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return meta, fmt.Errorf("failed to fetch metadata: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return meta, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	// The mapping may be served wrapped in JavaScript, e.g.
+	// `var latestUpdates = {...};`, so only decode the outer object
+	start, end := bytes.IndexByte(body, '{'), bytes.LastIndexByte(body, '}')
+	if start < 0 || end < start {
+		return meta, fmt.Errorf("metadata contains no JSON object")
+	}
+
+	if err := json.Unmarshal(body[start:end+1], &meta); err != nil {
+		return meta, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+
+	return meta, nil
+}
+
+// refreshMetadata updates the tag and prefix name tables from METAURL
+func refreshMetadata(db *sql.DB) {
+	if METAURL == "" {
+		return
+	}
+
+	meta, err := getMetadata()
+	if err != nil {
+		log.Printf("Metadata refresh failed: %v", err)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Metadata refresh failed: %v", err)
+		return
+	}
+	defer tx.Rollback()
+
+	tagQuery := `
+		insert into tag (id, name) values (?, ?)
+		on conflict (id) do update set name = excluded.name;
+	`
+	for k, name := range meta.Tags {
+		id, err := strconv.Atoi(k)
+		if err != nil {
+			continue
+		}
+		if _, err := tx.Exec(tagQuery, id, sanitizeText(name)); err != nil {
+			log.Printf("Metadata refresh failed: %v", err)
+			return
+		}
+	}
+
+	prefixQuery := `
+		insert into prefix (id, name, category) values (?, ?, ?)
+		on conflict (id) do update set name = excluded.name, category = excluded.category;
+	`
+	for _, group := range meta.Prefixes["games"] {
+		for _, p := range group.Prefixes {
+			if _, err := tx.Exec(prefixQuery, p.ID, sanitizeText(p.Name), group.Name); err != nil {
+				log.Printf("Metadata refresh failed: %v", err)
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Metadata refresh failed: %v", err)
+		return
+	}
+	resetTagCounts()
+
+	log.Printf("Metadata refreshed: %d tags, %d prefix groups", len(meta.Tags), len(meta.Prefixes["games"]))
+}
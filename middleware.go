@@ -0,0 +1,77 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+var ACCESSLOG bool // log every request
+
+// responseRecorder captures the status and size of a response
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// withBasePath serves next under BASEPATH
+func withBasePath(next http.Handler) http.Handler {
+	if BASEPATH == "" {
+		return next
+	}
+	return http.StripPrefix(BASEPATH, next)
+}
+
+// logRequests writes an access log line per request
+func logRequests(next http.Handler) http.Handler {
+	if !ACCESSLOG {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		log.Printf("method=%s path=%q status=%d bytes=%d client=%s duration=%s",
+			r.Method, r.URL.RequestURI(), rec.status, rec.bytes, client, time.Since(start).Round(time.Microsecond))
+	})
+}
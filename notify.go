@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	WEBHOOKURL     string // URL the watched game updates are POSTed to as JSON
+	DISCORDWEBHOOK string // Discord webhook URL the updates are posted to as messages
+	TELEGRAMTOKEN  string // Telegram bot token, used with F95_RSS_TELEGRAM_CHAT
+	TELEGRAMCHAT   string // Telegram chat id or @channel the bot posts the updates to
+)
+
+// telegramAPI is the Bot API base URL
+var telegramAPI = "https://api.telegram.org"
+
+const notifyAttempts = 3 // sends per update and channel before giving up until the next run
+
+var notifyBackoff = time.Second // wait before the second attempt, doubled after each failure
+
+var notifyClient = &http.Client{Timeout: 15 * time.Second}
+
+// notifier delivers update events over one channel
+type notifier struct {
+	channel string
+	send    func(e UpdateEvent) error
+}
+
+// notifiers returns the configured channels
+func notifiers() []notifier {
+	var out []notifier
+	if WEBHOOKURL != "" {
+		out = append(out, notifier{channel: "webhook", send: sendWebhook})
+	}
+	if DISCORDWEBHOOK != "" {
+		out = append(out, notifier{channel: "discord", send: sendDiscord})
+	}
+	if TELEGRAMTOKEN != "" {
+		out = append(out, notifier{channel: "telegram", send: sendTelegram})
+	}
+	return out
+}
+
+func sendWebhook(e UpdateEvent) error {
+	return postJSON("webhook", WEBHOOKURL, e)
+}
+
+// updateText is the one line message of the chat channels
+func updateText(e UpdateEvent) string {
+	if e.Version == "" {
+		return e.Title + " was updated"
+	}
+	return e.Title + " updated to " + e.Version
+}
+
+// discordMessage is the body of a Discord webhook execution
+type discordMessage struct {
+	Content string         `json:"content"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+func sendDiscord(e UpdateEvent) error {
+	msg := discordMessage{
+		Content: updateText(e),
+		Embeds:  []discordEmbed{{Title: e.Title, URL: e.Link}},
+	}
+	if e.Version != "" {
+		msg.Embeds[0].Description = "Version " + e.Version
+	}
+	return postJSON("Discord", DISCORDWEBHOOK, msg)
+}
+
+// telegramMessage is the body of a Bot API sendMessage call
+type telegramMessage struct {
+	ChatID string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+func sendTelegram(e UpdateEvent) error {
+	msg := telegramMessage{ChatID: TELEGRAMCHAT, Text: updateText(e) + "\n" + e.Link}
+	return postJSON("Telegram", telegramAPI+"/bot"+TELEGRAMTOKEN+"/sendMessage", msg)
+}
+
+// postJSON POSTs v as JSON, failing on any non-2xx answer. The URL is left
+// out of errors since the chat channels carry their credentials in it.
+func postJSON(name, u string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	resp, err := notifyClient.Post(u, "application/json", bytes.NewReader(body))
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s request failed: %w", name, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s answered %s", name, resp.Status)
+	}
+	return nil
+}
+
+// notifyMu keeps the quiet hours flush from racing an update's sends
+var notifyMu sync.Mutex
+
+// notifyUpdates announces the changed games of the watchlist on every
+// channel. The notified table remembers each game version sent per channel,
+// so retries and restarts never announce the same update twice; a send is
+// recorded only once it succeeded. During quiet hours the games are queued
+// instead and go out with the first call after them; a queued game leaves
+// the queue once every channel has it, so failed sends are retried.
+func notifyUpdates(db *sql.DB, watched, changed []int) {
+	channels := notifiers()
+	if len(channels) == 0 {
+		return
+	}
+
+	notifyMu.Lock()
+	defer notifyMu.Unlock()
+
+	watch := make(map[int]bool, len(watched))
+	for _, id := range watched {
+		watch[id] = true
+	}
+	var pending []int
+	for _, id := range changed {
+		if watch[id] {
+			pending = append(pending, id)
+		}
+	}
+
+	if quietHours.contains(time.Now()) {
+		if len(pending) > 0 {
+			queueNotifications(db, pending)
+		}
+		return
+	}
+
+	queued, err := queryIDs(db, "select game_id from notify_queue order by queued;")
+	if err != nil {
+		log.Printf("Failed to read queued notifications: %v", err)
+	}
+	inQueue := make(map[int]bool, len(queued))
+	for _, id := range queued {
+		inQueue[id] = true
+		if !watch[id] {
+			// Dropped from the watchlist while it waited
+			dequeueNotification(db, id)
+			continue
+		}
+		if !slices.Contains(pending, id) {
+			pending = append(pending, id)
+		}
+	}
+
+	for _, id := range pending {
+		e := UpdateEvent{ID: id, Link: fmt.Sprintf("https://f95zone.to/threads/%d", id)}
+		err := db.QueryRow("select title, coalesce(version, '') from game where id = ?;", id).Scan(&e.Title, &e.Version)
+		if err != nil {
+			log.Printf("Failed to load game %d for notifications: %v", id, err)
+			continue
+		}
+
+		delivered := true
+		for _, n := range channels {
+			var sent bool
+			err := db.QueryRow(
+				"select exists (select 1 from notified where game_id = ? and version = ? and channel = ?);",
+				id, e.Version, n.channel,
+			).Scan(&sent)
+			if err != nil {
+				log.Printf("Failed to check %s notifications of game %d: %v", n.channel, id, err)
+				delivered = false
+				continue
+			}
+			if sent {
+				continue
+			}
+
+			if err := sendWithRetry(n, e); err != nil {
+				log.Printf("Failed to notify %s of game %d: %v", n.channel, id, err)
+				delivered = false
+				continue
+			}
+
+			_, err = db.Exec(
+				"insert into notified (game_id, version, channel) values (?, ?, ?) on conflict do nothing;",
+				id, e.Version, n.channel,
+			)
+			if err != nil {
+				log.Printf("Failed to record %s notification of game %d: %v", n.channel, id, err)
+			}
+		}
+		if delivered && inQueue[id] {
+			dequeueNotification(db, id)
+		}
+	}
+}
+
+// sendWithRetry tries a send notifyAttempts times with growing pauses
+func sendWithRetry(n notifier, e UpdateEvent) error {
+	backoff := notifyBackoff
+	var err error
+	for attempt := 1; attempt <= notifyAttempts; attempt++ {
+		if err = n.send(e); err == nil {
+			return nil
+		}
+		if attempt < notifyAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+// testReceiver records the JSON bodies POSTed to it by path and answers
+// with status
+type testReceiver struct {
+	sync.Mutex
+	status int
+	bodies map[string][]string
+}
+
+func newTestReceiver(t *testing.T) (*testReceiver, *httptest.Server) {
+	rec := &testReceiver{status: http.StatusOK, bodies: map[string][]string{}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.Lock()
+		defer rec.Unlock()
+		rec.bodies[r.URL.Path] = append(rec.bodies[r.URL.Path], string(body))
+		w.WriteHeader(rec.status)
+	}))
+	t.Cleanup(srv.Close)
+	return rec, srv
+}
+
+// setNotifyConfig overrides the notification settings for one test
+func setNotifyConfig(t *testing.T, webhook, discord, token, chat, api string) {
+	oldWebhook, oldDiscord, oldToken, oldChat, oldAPI, oldBackoff := WEBHOOKURL, DISCORDWEBHOOK, TELEGRAMTOKEN, TELEGRAMCHAT, telegramAPI, notifyBackoff
+	WEBHOOKURL, DISCORDWEBHOOK, TELEGRAMTOKEN, TELEGRAMCHAT, telegramAPI, notifyBackoff = webhook, discord, token, chat, api, 0
+	t.Cleanup(func() {
+		WEBHOOKURL, DISCORDWEBHOOK, TELEGRAMTOKEN, TELEGRAMCHAT, telegramAPI, notifyBackoff = oldWebhook, oldDiscord, oldToken, oldChat, oldAPI, oldBackoff
+	})
+}
+
+func TestChatPayloads(t *testing.T) {
+	rec, srv := newTestReceiver(t)
+	setNotifyConfig(t, "", srv.URL+"/discord", "123:abc", "@updates", srv.URL)
+
+	tests := []struct {
+		event    UpdateEvent
+		discord  discordMessage
+		telegram telegramMessage
+	}{
+		{
+			UpdateEvent{ID: 1, Title: "Game", Version: "0.2", Link: "https://f95zone.to/threads/1"},
+			discordMessage{Content: "Game updated to 0.2", Embeds: []discordEmbed{{Title: "Game", URL: "https://f95zone.to/threads/1", Description: "Version 0.2"}}},
+			telegramMessage{ChatID: "@updates", Text: "Game updated to 0.2\nhttps://f95zone.to/threads/1"},
+		},
+		{
+			UpdateEvent{ID: 2, Title: "Other", Link: "https://f95zone.to/threads/2"},
+			discordMessage{Content: "Other was updated", Embeds: []discordEmbed{{Title: "Other", URL: "https://f95zone.to/threads/2"}}},
+			telegramMessage{ChatID: "@updates", Text: "Other was updated\nhttps://f95zone.to/threads/2"},
+		},
+	}
+	for i, tt := range tests {
+		for _, n := range notifiers() {
+			if err := n.send(tt.event); err != nil {
+				t.Fatalf("%s: %v", n.channel, err)
+			}
+		}
+
+		var discord discordMessage
+		if err := json.Unmarshal([]byte(rec.bodies["/discord"][i]), &discord); err != nil {
+			t.Fatal(err)
+		}
+		if discord.Content != tt.discord.Content || len(discord.Embeds) != 1 || discord.Embeds[0] != tt.discord.Embeds[0] {
+			t.Errorf("Discord message %+v, want %+v", discord, tt.discord)
+		}
+
+		var telegram telegramMessage
+		if err := json.Unmarshal([]byte(rec.bodies["/bot123:abc/sendMessage"][i]), &telegram); err != nil {
+			t.Fatal(err)
+		}
+		if telegram != tt.telegram {
+			t.Errorf("Telegram message %+v, want %+v", telegram, tt.telegram)
+		}
+	}
+
+	rec.status = http.StatusUnauthorized
+	err := sendTelegram(tests[0].event)
+	if err == nil {
+		t.Fatal("no error for a rejected send")
+	}
+	if got := err.Error(); got != "Telegram answered 401 Unauthorized" {
+		t.Errorf("error %q", got)
+	}
+}
+
+// TestQueuedNotificationRetried checks that a game held back by quiet
+// hours stays queued until its send succeeds
+func TestQueuedNotificationRetried(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	rec, srv := newTestReceiver(t)
+	setNotifyConfig(t, srv.URL+"/hook", "", "", "", "")
+	db := newTestDB(t)
+	insertGame(db, 1, "Game", "0.1", 4, "", 0)
+	queueNotifications(db, []int{1, 2})
+
+	queued := func() []int {
+		ids, err := queryIDs(db, "select game_id from notify_queue order by game_id;")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ids
+	}
+
+	// Game 2 left the watchlist and is dropped, game 1 fails to send
+	rec.status = http.StatusInternalServerError
+	notifyUpdates(db, []int{1}, nil)
+	if got := queued(); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("queue after a failed send is %v, want [1]", got)
+	}
+	if n := len(rec.bodies["/hook"]); n != notifyAttempts {
+		t.Errorf("%d attempts, want %d", n, notifyAttempts)
+	}
+
+	rec.status = http.StatusOK
+	notifyUpdates(db, []int{1}, nil)
+	if got := queued(); len(got) != 0 {
+		t.Fatalf("queue after a successful send is %v, want empty", got)
+	}
+
+	// Already announced, so the next run sends nothing
+	sent := len(rec.bodies["/hook"])
+	queueNotifications(db, []int{1})
+	notifyUpdates(db, []int{1}, nil)
+	if n := len(rec.bodies["/hook"]); n != sent {
+		t.Errorf("announced the same version again")
+	}
+	if got := queued(); len(got) != 0 {
+		t.Errorf("queue of an already announced game is %v, want empty", got)
+	}
+}
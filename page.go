@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// paginate cuts a page out of ids when the request has ?limit= or ?offset=,
+// announcing the total in X-Total-Count and the neighbouring pages in a
+// Link header. Pages are at most MAXITEMS long, so the links walk the whole
+// list instead of skipping what capItems would cut. Requests without either
+// get ids back unchanged.
+func paginate(w http.ResponseWriter, r *http.Request, ids []int) ([]int, error) {
+	q := r.URL.Query()
+	if !q.Has("limit") && !q.Has("offset") {
+		return ids, nil
+	}
+
+	limit, offset := len(ids), 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid limit %q", v)
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid offset %q", v)
+		}
+		offset = n
+	}
+	if MAXITEMS > 0 {
+		limit = min(limit, MAXITEMS)
+	}
+
+	total := len(ids)
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	var links []string
+	if offset+limit < total {
+		links = append(links, pageLink(r, limit, offset+limit, "next"))
+	}
+	if offset > 0 {
+		links = append(links, pageLink(r, limit, max(0, offset-limit), "prev"))
+	}
+	for _, l := range links {
+		w.Header().Add("Link", l)
+	}
+
+	if offset >= total {
+		return []int{}, nil
+	}
+	return ids[offset:min(total, offset+limit)], nil
+}
+
+// pageLink renders a Link header entry for the request with another page
+func pageLink(r *http.Request, limit, offset int, rel string) string {
+	u, _ := url.Parse(requestURL(r))
+	q := u.Query()
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("offset", strconv.Itoa(offset))
+	u.RawQuery = q.Encode()
+	return "<" + u.String() + `>; rel="` + rel + `"`
+}
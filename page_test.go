@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestPaginateMaxItems checks pages are clamped to MAXITEMS, with links
+// that step by the clamped size so no game falls between two pages
+func TestPaginateMaxItems(t *testing.T) {
+	old := MAXITEMS
+	MAXITEMS = 10
+	defer func() { MAXITEMS = old }()
+
+	ids := make([]int, 25)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+
+	r := httptest.NewRequest("GET", "http://example.com/feed?limit=100&offset=10", nil)
+	w := httptest.NewRecorder()
+	page, err := paginate(w, r, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 10 || page[0] != 11 {
+		t.Errorf("page = %v, want ids 11 to 20", page)
+	}
+	if got := w.Header().Get("X-Total-Count"); got != "25" {
+		t.Errorf("X-Total-Count = %q, want 25", got)
+	}
+	links := strings.Join(w.Header().Values("Link"), ", ")
+	for _, want := range []string{"limit=10&offset=20>; rel=\"next\"", "limit=10&offset=0>; rel=\"prev\""} {
+		if !strings.Contains(links, want) {
+			t.Errorf("Link = %s, missing %s", links, want)
+		}
+	}
+	if len(capItems(w, page)) != len(page) {
+		t.Error("capItems cut a clamped page")
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// pinMarker prefixes the titles of pinned items
+const pinMarker = "📌 "
+
+// pinFirst moves pinned items to the top of the feed, keeping the order
+// of the id file within both groups
+func pinFirst(items []*Item) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Pinned && !items[j].Pinned
+	})
+	for _, item := range items {
+		if item.Pinned {
+			item.Title = pinMarker + item.Title
+		}
+	}
+}
+
+// servePin pins or unpins the game of the {id} path value
+func servePin(db *sql.DB, pin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil || id <= 0 {
+			writeError(w, "Invalid id", http.StatusBadRequest)
+			return
+		}
+
+		query := "insert into pinned (game_id) values (?) on conflict (game_id) do nothing;"
+		if !pin {
+			query = "delete from pinned where game_id = ?;"
+		}
+		if _, err := db.Exec(query, id); err != nil {
+			writeError(w, "Error updating pin", http.StatusInternalServerError)
+			return
+		}
+		touchFeedState(db)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
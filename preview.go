@@ -0,0 +1,74 @@
+package main
+
+import (
+	"database/sql"
+	"html/template"
+	"log"
+	"net/http"
+)
+
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>f95-rss</title>
+<link rel="alternate" type="application/rss+xml" title="F95zone Latest Updates" href="{{.Base}}/feed">
+<style>
+body { font-family: sans-serif; max-width: 48em; margin: 2em auto; padding: 0 1em; }
+li { display: flex; gap: 1em; align-items: center; margin-bottom: 1em; }
+img { width: 120px; height: auto; }
+.meta { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>f95-rss</h1>
+<p class="meta">
+{{len .Items}} watched games &middot; <a href="{{.Base}}/feed">RSS feed</a>
+{{with .Run}} &middot; last update {{.Started.Format "2006-01-02 15:04"}}{{with .Error}}, failed: {{.}}{{else}}, {{.Changed}} changed{{end}}{{end}}
+</p>
+<ul>
+{{range .Items}}<li>
+{{if .Cover}}<img src="{{$.Base}}/img?url={{urlquery .Cover}}&amp;w=240" alt="">{{end}}
+<div><a href="{{.Link}}">{{.Name}}</a>{{with .Version}} <span class="meta">{{.}}</span>{{end}}
+<div class="meta">updated {{.PubDate.Format "2006-01-02 15:04"}}</div></div>
+</li>
+{{else}}<li>No games yet. Check F95_RSS_ID_FILE and the update log.</li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// servePreview renders the watchlist as the feed shows it, as a human
+// readable status page
+func servePreview(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ids, err := readIDsFromFile(IDFILE)
+		if err != nil {
+			http.Error(w, "Error reading IDs from file", http.StatusInternalServerError)
+			return
+		}
+
+		items, err := fetchDataFromDB(r.Context(), db, capItems(w, ids), FeedOptions{})
+		if err != nil {
+			feedError(w, err, "Error loading games")
+			return
+		}
+
+		data := struct {
+			Base  string
+			Items []*Item
+			Run   *UpdateRun
+		}{Base: BASEPATH, Items: items}
+
+		runs, err := fetchRuns(db, 1)
+		if err == nil && len(runs) > 0 {
+			data.Run = &runs[0]
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := previewTemplate.Execute(w, data); err != nil {
+			log.Printf("Failed to render preview: %v", err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+var PRUNECRON string // schedule of the database cleanup
+
+// pruneDatabase removes rows left behind by deleted games and unused hosts,
+// then compacts the file
+func pruneDatabase(db *sql.DB) {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+
+	queries := []string{
+		`delete from cover where game_id not in (select id from game);`,
+		`delete from preview where game_id not in (select id from game);`,
+		`delete from tags where game_id not in (select id from game);`,
+		`delete from prefixes where game_id not in (select id from game);`,
+		`delete from version_history where game_id not in (select id from game);`,
+		`delete from tag_change where game_id not in (select id from game);`,
+		`delete from notified where game_id not in (select id from game);`,
+		`delete from host where id not in (select host_id from cover union select host_id from preview);`,
+		`delete from creator where id not in (select creator_id from game where creator_id is not null);`,
+	}
+
+	var removed int64
+	for _, q := range queries {
+		res, err := db.Exec(q)
+		if err != nil {
+			log.Printf("Prune failed: %v", err)
+			return
+		}
+		n, _ := res.RowsAffected()
+		removed += n
+	}
+
+	if _, err := db.Exec("vacuum;"); err != nil {
+		log.Printf("Vacuum failed: %v", err)
+	}
+
+	log.Printf("Pruned %d orphaned rows", removed)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+var QUIETHOURS string // HH:MM-HH:MM of F95_RSS_TZ during which notifications are held back, e.g. 22:00-07:00
+
+// quietWindow is a daily range of minutes after midnight; start > end
+// crosses midnight
+type quietWindow struct {
+	start, end int
+}
+
+// parseQuietHours reads a HH:MM-HH:MM window. An empty value is a nil window.
+func parseQuietHours(v string) (*quietWindow, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	var sh, sm, eh, em int
+	if _, err := fmt.Sscanf(v, "%d:%d-%d:%d", &sh, &sm, &eh, &em); err != nil {
+		return nil, fmt.Errorf("F95_RSS_QUIET_HOURS %q is not HH:MM-HH:MM", v)
+	}
+	for _, n := range []int{sh, eh} {
+		if n < 0 || n > 23 {
+			return nil, fmt.Errorf("F95_RSS_QUIET_HOURS %q has an invalid hour", v)
+		}
+	}
+	for _, n := range []int{sm, em} {
+		if n < 0 || n > 59 {
+			return nil, fmt.Errorf("F95_RSS_QUIET_HOURS %q has an invalid minute", v)
+		}
+	}
+
+	w := &quietWindow{start: sh*60 + sm, end: eh*60 + em}
+	if w.start == w.end {
+		return nil, fmt.Errorf("F95_RSS_QUIET_HOURS %q is an empty window", v)
+	}
+	return w, nil
+}
+
+// quietHours is the configured window; validateConfig rejects invalid ones
+var quietHours *quietWindow
+
+// contains reports whether t falls in the window, in FEEDTZ
+func (w *quietWindow) contains(t time.Time) bool {
+	if w == nil {
+		return false
+	}
+	t = t.In(FEEDTZ)
+	m := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return m >= w.start && m < w.end
+	}
+	return m >= w.start || m < w.end
+}
+
+// nextEnd returns the first end of the window after t
+func (w *quietWindow) nextEnd(t time.Time) time.Time {
+	local := t.In(FEEDTZ)
+	end := time.Date(local.Year(), local.Month(), local.Day(), w.end/60, w.end%60, 0, 0, FEEDTZ)
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// queueNotifications holds ids back until the quiet hours are over. The
+// queue is a table so restarts don't lose it.
+func queueNotifications(db *sql.DB, ids []int) {
+	for _, id := range ids {
+		_, err := db.Exec("insert into notify_queue (game_id) values (?) on conflict (game_id) do nothing;", id)
+		if err != nil {
+			log.Printf("Failed to queue notification of game %d: %v", id, err)
+		}
+	}
+	log.Printf("Quiet hours, holding back notifications of %d games", len(ids))
+}
+
+// dequeueNotification removes a game from the queue once it went out
+func dequeueNotification(db *sql.DB, id int) {
+	if _, err := db.Exec("delete from notify_queue where game_id = ?;", id); err != nil {
+		log.Printf("Failed to dequeue notification of game %d: %v", id, err)
+	}
+}
+
+// watchQuietHours sends the held back notifications whenever the quiet
+// hours end, so they don't wait for the next update
+func watchQuietHours(db *sql.DB) {
+	if quietHours == nil {
+		return
+	}
+	for {
+		time.Sleep(time.Until(quietHours.nextEnd(time.Now())))
+
+		ids, err := readIDsFromFile(IDFILE)
+		if err != nil {
+			log.Printf("Failed to flush notifications: %v", err)
+			continue
+		}
+		notifyUpdates(db, ids, nil)
+	}
+}
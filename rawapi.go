@@ -0,0 +1,188 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rawAPITTL is how long a fetched API response answers record lookups
+const rawAPITTL = time.Minute
+
+// rawAPI caches the records of the last API response, keyed by thread id
+var rawAPI struct {
+	sync.Mutex
+	fetched time.Time
+	records map[int]json.RawMessage
+}
+
+// fetchRawRecords returns every record of the live API response as it was
+// sent, including the fields that are not stored
+func fetchRawRecords() (map[int]json.RawMessage, error) {
+	rawAPI.Lock()
+	defer rawAPI.Unlock()
+	if rawAPI.records != nil && time.Since(rawAPI.fetched) < rawAPITTL {
+		return rawAPI.records, nil
+	}
+
+	body, err := openRawAPI()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var data struct {
+		Msg struct {
+			Data []json.RawMessage `json:"data"`
+		} `json:"msg"`
+	}
+	var r io.Reader = body
+	if APIMAXBYTES > 0 {
+		r = io.LimitReader(body, int64(APIMAXBYTES))
+	}
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to read API response: %w", err)
+	}
+
+	records := make(map[int]json.RawMessage, len(data.Msg.Data))
+	for _, raw := range data.Msg.Data {
+		var record struct {
+			ThreadID int `json:"thread_id"`
+		}
+		if json.Unmarshal(raw, &record) == nil && record.ThreadID > 0 {
+			records[record.ThreadID] = raw
+		}
+	}
+
+	rawAPI.fetched = time.Now()
+	rawAPI.records = records
+	return records, nil
+}
+
+// openRawAPI opens the API response, or the saved one of F95_RSS_API_FILE
+func openRawAPI() (io.ReadCloser, error) {
+	if APIFILE != "" {
+		return os.Open(APIFILE)
+	}
+
+	u, err := apiURL()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := apiClient.Get(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch API: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("API returned %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// Serve the raw API record of one game, for checking what the source sends
+func serveRawAPI(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeError(w, "Invalid game id", http.StatusBadRequest)
+		return
+	}
+
+	records, err := fetchRawRecords()
+	if err != nil {
+		writeError(w, "Error fetching API: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	record, ok := records[id]
+	if !ok {
+		writeError(w, "Game not in the current API response", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(record)
+}
+
+// Serve POST /admin/refresh/{id}: re-ingest one game from a fresh API
+// response and return the stored record. The game goes through the same
+// filters as a scheduled update, and a change is published the same way.
+func serveRefresh(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil || id <= 0 {
+			writeError(w, "Invalid game id", http.StatusBadRequest)
+			return
+		}
+
+		rawAPI.Lock()
+		rawAPI.records = nil
+		rawAPI.Unlock()
+		records, err := fetchRawRecords()
+		if err != nil {
+			writeError(w, "Error fetching API: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		record, ok := records[id]
+		if !ok {
+			writeError(w, fmt.Sprintf("Game %d is not in the current API response", id), http.StatusNotFound)
+			return
+		}
+
+		var f F95DATA
+		if err := json.Unmarshal(record, &f); err != nil {
+			writeError(w, "Malformed API record: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		updateMu.Lock()
+		ensureDatabase(db)
+		allow, err := loadAllowlist(db)
+		if err != nil {
+			updateMu.Unlock()
+			writeError(w, "Invalid allowlist: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		aliases, err := loadTagAliases(db)
+		if err != nil {
+			updateMu.Unlock()
+			writeError(w, "Error loading tag aliases", http.StatusInternalServerError)
+			return
+		}
+		if reason := filterGame(allow, aliases, &f); reason != notSkipped {
+			updateMu.Unlock()
+			writeError(w, fmt.Sprintf("Game %d is not ingested: %s", id, reason), http.StatusUnprocessableEntity)
+			return
+		}
+		updated, warning := ingestGame(db, f, gameHash(f))
+		updateMu.Unlock()
+		resetTagCounts()
+		if warning != "" {
+			log.Printf("Refresh of game %d: %s", id, warning)
+		}
+
+		var changed []int
+		if updated {
+			changed = []int{id}
+		}
+		ids, err := readIDsFromFile(IDFILE)
+		if err != nil {
+			writeError(w, "Error reading IDs", http.StatusInternalServerError)
+			return
+		}
+		publishFeed(db, ids, changed) // logs its own errors
+
+		game, err := fetchGameDetail(db, id)
+		if err != nil {
+			writeError(w, "Error loading game", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, game)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+)
+
+// queryMaps runs a query and returns its rows as column name to value maps,
+// for dumping stored data as is
+func queryMaps(db *sql.DB, query string, args ...any) ([]map[string]any, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	out := []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, c := range columns {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[c] = values[i]
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// rawGameQueries select the stored state of a game, keyed by the name they
+// appear under in the dump
+var rawGameQueries = []struct{ key, query string }{
+	{"covers", `
+		select c.id, c.host_id, h.base || c.path as url from cover c
+		join host h on h.id = c.host_id
+		where c.game_id = ? order by c.id;
+	`},
+	{"previews", `
+		select p.id, p.host_id, h.base || p.path as url from preview p
+		join host h on h.id = p.host_id
+		where p.game_id = ? order by p.id;
+	`},
+	{"tags", `
+		select t.tag_id as id, n.name from tags t
+		left join tag n on n.id = t.tag_id
+		where t.game_id = ? order by t.tag_id;
+	`},
+	{"prefixes", `
+		select p.prefix_id as id, n.name, n.category from prefixes p
+		left join prefix n on n.id = p.prefix_id
+		where p.game_id = ? order by p.prefix_id;
+	`},
+	{"version_history", "select id, version, recorded from version_history where game_id = ? order by id;"},
+}
+
+// serveRawGame dumps everything stored about the game of the {id} path
+// value: the game row with its creator, and every cover, preview, tag,
+// prefix and recorded version with their row ids
+func serveRawGame(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil || id <= 0 {
+			writeError(w, "Invalid game id", http.StatusBadRequest)
+			return
+		}
+
+		games, err := queryMaps(db, `
+			select g.*, c.name as creator from game g
+			left join creator c on c.id = g.creator_id
+			where g.id = ?;
+		`, id)
+		if err != nil {
+			writeError(w, "Error loading game", http.StatusInternalServerError)
+			return
+		}
+		if len(games) == 0 {
+			writeError(w, "Game not found", http.StatusNotFound)
+			return
+		}
+
+		out := map[string]any{"game": games[0]}
+		for _, q := range rawGameQueries {
+			rows, err := queryMaps(db, q.query, id)
+			if err != nil {
+				writeError(w, "Error loading "+q.key, http.StatusInternalServerError)
+				return
+			}
+			out[q.key] = rows
+		}
+		writeJSON(w, out)
+	}
+}
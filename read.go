@@ -0,0 +1,72 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+)
+
+// dropRead removes the items whose guid was marked read
+func dropRead(db *sql.DB, items []*Item) ([]*Item, error) {
+	rows, err := db.Query("select guid from read_state;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	read := map[string]bool{}
+	for rows.Next() {
+		var guid string
+		if err := rows.Scan(&guid); err != nil {
+			return nil, err
+		}
+		read[guid] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := items[:0]
+	for _, item := range items {
+		if item.GUID == nil || !read[item.GUID.Value] {
+			out = append(out, item)
+		}
+	}
+	return out, nil
+}
+
+// serveMarkRead marks the item of the {guid} path value read, or unread
+// when read is false
+func serveMarkRead(db *sql.DB, read bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		guid := r.PathValue("guid")
+		if guid == "" {
+			writeError(w, "Missing guid", http.StatusBadRequest)
+			return
+		}
+
+		query := "insert into read_state (guid) values (?) on conflict (guid) do nothing;"
+		if !read {
+			query = "delete from read_state where guid = ?;"
+		}
+		if _, err := db.Exec(query, guid); err != nil {
+			writeError(w, "Error updating read state", http.StatusInternalServerError)
+			return
+		}
+		touchFeedState(db)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// serveClearRead marks every item unread
+func serveClearRead(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := db.Exec("delete from read_state;"); err != nil {
+			writeError(w, "Error clearing read state", http.StatusInternalServerError)
+			return
+		}
+		touchFeedState(db)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var ADMINTOKEN string // bearer token for /admin endpoints
+
+// rebuildConfirm must be sent as ?confirm= to rebuild the database
+const rebuildConfirm = "drop-all-data"
+
+// updateMu keeps a rebuild from running alongside an update
+var updateMu sync.Mutex
+
+// requireAdmin rejects requests without the admin bearer token. Without a
+// configured token the admin endpoints stay open, except those marked
+// sensitive, which are disabled.
+func requireAdmin(sensitive bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ADMINTOKEN == "" {
+			if sensitive {
+				writeError(w, "Disabled: F95_RSS_ADMIN_TOKEN is not set", http.StatusForbidden)
+				return
+			}
+			next(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(ADMINTOKEN)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="f95-rss"`)
+			writeError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// dropTables drops every table, and with them their indexes and triggers
+func dropTables(tx *sql.Tx) ([]string, error) {
+	rows, err := tx.Query(sqlDialect.tablesQuery() + ";")
+	if err != nil {
+		return nil, err
+	}
+	tables := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range tables {
+		if _, err := tx.Exec(sqlDialect.dropTable(name)); err != nil {
+			return nil, err
+		}
+	}
+	return tables, nil
+}
+
+// rebuildDatabase drops and recreates the schema in one transaction
+func rebuildDatabase(db *sql.DB) ([]string, error) {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	dropped, err := dropTables(tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := createTables(tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	migrateDatabase(db)
+	resetTagCounts()
+	return dropped, nil
+}
+
+// serveRebuild wipes the database and ingests the API from scratch. It needs
+// the admin token, a POST and ?confirm=drop-all-data.
+func serveRebuild(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("confirm") != rebuildConfirm {
+			writeError(w, "Confirm with confirm="+rebuildConfirm, http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("Rebuilding the database on request from %s", r.RemoteAddr)
+		dropped, err := rebuildDatabase(db)
+		if err != nil {
+			log.Printf("Rebuild failed: %v", err)
+			writeError(w, "Error rebuilding database", http.StatusInternalServerError)
+			return
+		}
+
+		// The schema is already rebuilt at this point, so a failed update
+		// still reports what was dropped alongside the error. updateDatabase
+		// logs the failure itself.
+		changed, err := updateDatabase(db)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			writeJSON(w, map[string]any{
+				"dropped_tables": dropped,
+				"error":          "Error updating database: " + err.Error(),
+				"code":           http.StatusBadGateway,
+			})
+			return
+		}
+
+		var games int
+		if err := db.QueryRow("select count(*) from game;").Scan(&games); err != nil {
+			writeError(w, "Error counting games", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, map[string]any{
+			"dropped_tables": dropped,
+			"games":          games,
+			"changed":        len(changed),
+		})
+	}
+}
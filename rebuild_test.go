@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+
+	tests := []struct {
+		name      string
+		token     string
+		sensitive bool
+		auth      string
+		want      int
+	}{
+		{"no token configured", "", false, "", http.StatusNoContent},
+		{"sensitive without token configured", "", true, "Bearer anything", http.StatusForbidden},
+		{"missing header", "secret", false, "", http.StatusUnauthorized},
+		{"wrong token", "secret", true, "Bearer wrong", http.StatusUnauthorized},
+		{"not a bearer", "secret", true, "Basic secret", http.StatusUnauthorized},
+		{"right token", "secret", true, "Bearer secret", http.StatusNoContent},
+	}
+
+	defer func(token string) { ADMINTOKEN = token }(ADMINTOKEN)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ADMINTOKEN = tt.token
+			r := httptest.NewRequest(http.MethodPost, "/ids/1/pin", nil)
+			if tt.auth != "" {
+				r.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			requireAdmin(tt.sensitive, ok)(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Default number of games in the recommended feed
+const recommendedLimit = 50
+
+// fetchRecommended scores every unblocked game outside the watchlist by its tags,
+// each tag weighing as many points as watched games carry it, and returns
+// the best limit games, highest score first
+func fetchRecommended(ctx context.Context, db *sql.DB, watched []int, limit int) ([]int, error) {
+	if len(watched) == 0 {
+		return []int{}, nil
+	}
+
+	args := make([]any, 0, len(watched)+1)
+	for _, id := range watched {
+		args = append(args, id)
+	}
+	args = append(args, limit)
+
+	query := `
+		with watched(id) as (values (cast(? as integer))` + strings.Repeat(", (?)", len(watched)-1) + `),
+		weight(tag_id, points) as (
+			select tag_id, count(*) from tags
+			where game_id in (select id from watched)
+			group by tag_id
+		)
+		select t.game_id from tags t
+		join weight w on w.tag_id = t.tag_id
+		where t.game_id not in (select id from watched)
+		and t.game_id not in (select game_id from blocked)
+		group by t.game_id
+		order by sum(w.points) desc, t.game_id desc
+		limit ?;
+	`
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Serve a discovery feed of unwatched games sharing tags with the watchlist
+func serveRecommended(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := recommendedLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		watched, err := readIDsFromFile(IDFILE)
+		if err != nil {
+			http.Error(w, "Error reading IDs from file", http.StatusInternalServerError)
+			return
+		}
+
+		ids, err := fetchRecommended(r.Context(), db, watched, limit)
+		if err != nil {
+			feedError(w, err, "Error scoring games")
+			return
+		}
+		ids = capItems(w, ids)
+
+		items, err := fetchDataFromDB(r.Context(), db, ids, parseFeedOptions(r))
+		if err != nil {
+			feedError(w, err, "Error generating feed")
+			return
+		}
+
+		writeFeed(w, r, newFeed(&Channel{
+			Title:       "F95zone Recommended",
+			Link:        "https://f95zone.com/latest",
+			Description: "Unwatched games sharing tags with the watchlist",
+		}, items))
+	}
+}
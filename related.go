@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+var RELATEDMAX int // other games of the creator linked per item, 0 to disable
+
+// RelatedGame links to another game of an item's creator
+type RelatedGame struct {
+	Title string
+	Link  string
+}
+
+// fetchRelated fills in up to n other games, most recently updated first,
+// for the creator of each item. All creators are looked up in one query.
+func fetchRelated(ctx context.Context, db *sql.DB, items []*Item, n int) error {
+	if n <= 0 {
+		return nil
+	}
+
+	var args []any
+	seen := map[int]bool{}
+	for _, item := range items {
+		if item.CreatorID != 0 && !seen[item.CreatorID] {
+			seen[item.CreatorID] = true
+			args = append(args, item.CreatorID)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	query := `
+		select id, title, creator_id from game
+		where creator_id in (?` + strings.Repeat(", ?", len(args)-1) + `)
+		order by updated desc, id desc;
+	`
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type game struct {
+		id    int
+		title string
+	}
+	byCreator := map[int][]game{}
+	for rows.Next() {
+		var g game
+		var creatorID int
+		if err := rows.Scan(&g.id, &g.title, &creatorID); err != nil {
+			return err
+		}
+		byCreator[creatorID] = append(byCreator[creatorID], g)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		for _, g := range byCreator[item.CreatorID] {
+			if len(item.Related) >= n {
+				break
+			}
+			if g.id == item.GameID {
+				continue
+			}
+			item.Related = append(item.Related, RelatedGame{
+				Title: g.title,
+				Link:  fmt.Sprintf("https://f95zone.to/threads/%d", g.id),
+			})
+		}
+	}
+	return nil
+}
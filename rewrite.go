@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// IMAGEREWRITE rewrites cover and screenshot URLs in feeds, e.g. to point
+// at a mirror. Rules are separated by ; and written from=>to. A from in
+// slashes is a regular expression whose groups to can use as $1, otherwise
+// it is replaced literally. Rules apply in order; stored URLs are kept.
+//
+// Only URLs handed to readers are rewritten: item descriptions, screenshot
+// items and the JSON Feed image. Items keep the original URL, so the /img
+// proxy behind the preview page and ?inline=1 downloads still fetch from
+// the F95_RSS_IMG_HOSTS allowlist rather than from the rewrite targets.
+//
+//	https://attachments.f95zone.to/=>https://img.example.com/
+//	/^https://[a-z]+\.f95zone\.to/(.*)$/=>https://img.example.com/$1
+var IMAGEREWRITE string
+
+// rewriteRule replaces from, or the matches of re, by to
+type rewriteRule struct {
+	from string
+	re   *regexp.Regexp
+	to   string
+}
+
+// parseRewriteRules reads IMAGEREWRITE
+func parseRewriteRules(v string) ([]rewriteRule, error) {
+	var rules []rewriteRule
+	for _, s := range strings.Split(v, ";") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(s, "=>")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" {
+			return nil, fmt.Errorf("F95_RSS_IMAGE_REWRITE: expected from=>to, got %q", s)
+		}
+
+		rule := rewriteRule{from: from, to: to}
+		if len(from) > 2 && strings.HasPrefix(from, "/") && strings.HasSuffix(from, "/") {
+			re, err := regexp.Compile(from[1 : len(from)-1])
+			if err != nil {
+				return nil, fmt.Errorf("F95_RSS_IMAGE_REWRITE: %q: %w", from, err)
+			}
+			rule.re = re
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// imageRewrites are the configured rules; validateConfig rejects invalid ones
+var imageRewrites []rewriteRule
+
+// rewriteImageURL applies every rule to an image URL. Inlined data: URIs
+// are left alone.
+func rewriteImageURL(u string) string {
+	if u == "" || strings.HasPrefix(u, "data:") {
+		return u
+	}
+	for _, r := range imageRewrites {
+		if r.re != nil {
+			u = r.re.ReplaceAllString(u, r.to)
+		} else {
+			u = strings.ReplaceAll(u, r.from, r.to)
+		}
+	}
+	return u
+}
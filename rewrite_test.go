@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRewriteImageURL(t *testing.T) {
+	rules, err := parseRewriteRules(`https://attachments.f95zone.to/=>https://img.example.com/; /^https://preview\.f95zone\.to/(.*)$/=>https://cdn.example.com/p/$1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(r []rewriteRule) { imageRewrites = r }(imageRewrites)
+	imageRewrites = rules
+
+	tests := []struct {
+		in, want string
+	}{
+		{"https://attachments.f95zone.to/2024/01/1_cover.jpg", "https://img.example.com/2024/01/1_cover.jpg"},
+		{"https://preview.f95zone.to/2024/01/1.jpg", "https://cdn.example.com/p/2024/01/1.jpg"},
+		{"https://other.example.org/1.jpg", "https://other.example.org/1.jpg"},
+		{"data:image/jpeg;base64,aHR0cHM6Ly9hdHRhY2htZW50cy5mOTV6b25lLnRvLw==", "data:image/jpeg;base64,aHR0cHM6Ly9hdHRhY2htZW50cy5mOTV6b25lLnRvLw=="},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := rewriteImageURL(tt.in); got != tt.want {
+			t.Errorf("rewriteImageURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	// The description shows the rewritten URL while the item keeps the
+	// original for the /img proxy and inlining
+	const cover = "https://attachments.f95zone.to/2024/01/1_cover.jpg"
+	item := &Item{Name: "Game", Link: "https://f95zone.to/threads/1", Cover: cover}
+	if d := describe(item); !strings.Contains(d, `src="https://img.example.com/2024/01/1_cover.jpg"`) {
+		t.Errorf("description %s doesn't use the rewritten cover", d)
+	}
+	if item.Cover != cover {
+		t.Errorf("item cover changed to %q", item.Cover)
+	}
+}
+
+func TestParseRewriteRules(t *testing.T) {
+	tests := []struct {
+		in      string
+		rules   int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"a=>b", 1, false},
+		{" a => b ; /c(.*)/=>d$1 ;", 2, false},
+		{"a=>", 1, false},
+		{"=>b", 0, true},
+		{"no arrow", 0, true},
+		{"/(/=>b", 0, true},
+	}
+	for _, tt := range tests {
+		rules, err := parseRewriteRules(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRewriteRules(%q) error %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if len(rules) != tt.rules {
+			t.Errorf("parseRewriteRules(%q) gave %d rules, want %d", tt.in, len(rules), tt.rules)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var RUNHISTORY int // update runs kept in update_run
+
+// UpdateRun is the audit record of one updateDatabase invocation
+type UpdateRun struct {
+	ID        int       `json:"id"`
+	Started   time.Time `json:"started"`
+	Duration  int64     `json:"duration_ms"`
+	Processed int       `json:"processed"`
+	Changed   int       `json:"changed"`
+	Error     string    `json:"error,omitempty"`
+	Warnings  []string  `json:"warnings,omitempty"`
+}
+
+// recordRun stores a finished run and prunes history beyond RUNHISTORY
+func recordRun(db *sql.DB, run *UpdateRun) {
+	run.Duration = time.Since(run.Started).Milliseconds()
+
+	query := `
+		insert into update_run (started, duration_ms, processed, changed, error, warnings)
+		values (?, ?, ?, ?, nullif(?, ''), nullif(?, ''));
+	`
+	warnings := strings.Join(run.Warnings, "\n")
+	if _, err := db.Exec(query, run.Started, run.Duration, run.Processed, run.Changed, run.Error, warnings); err != nil {
+		log.Printf("Failed to record update run: %v", err)
+		return
+	}
+
+	prune := `
+		delete from update_run where id not in (
+			select id from update_run order by id desc limit ?
+		);
+	`
+	if _, err := db.Exec(prune, max(RUNHISTORY, 1)); err != nil {
+		log.Printf("Failed to prune update runs: %v", err)
+	}
+}
+
+// fetchRuns returns the most recent update runs, newest first
+func fetchRuns(db *sql.DB, limit int) ([]UpdateRun, error) {
+	rows, err := db.Query(`
+		select id, started, duration_ms, processed, changed, coalesce(error, ''), coalesce(warnings, '')
+		from update_run order by id desc limit ?;
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []UpdateRun{}
+	for rows.Next() {
+		var run UpdateRun
+		var warnings string
+		if err := rows.Scan(&run.ID, &run.Started, &run.Duration, &run.Processed, &run.Changed, &run.Error, &warnings); err != nil {
+			return nil, err
+		}
+		if warnings != "" {
+			run.Warnings = strings.Split(warnings, "\n")
+		}
+		run.Started = run.Started.In(FEEDTZ)
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// Serve the update run history as JSON
+func serveRuns(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runs, err := fetchRuns(db, max(RUNHISTORY, 1))
+		if err != nil {
+			writeError(w, "Error reading update runs", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, runs)
+	}
+}
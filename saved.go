@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// Most items a saved search feed returns
+const savedFeedLimit = 100
+
+var savedFeedName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Create a saved search from form values: name plus the filter parameters
+func createSavedFeed(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			writeError(w, "Invalid form", http.StatusBadRequest)
+			return
+		}
+
+		name := r.Form.Get("name")
+		if !savedFeedName.MatchString(name) {
+			writeError(w, "Invalid name: use lowercase letters, digits, - and _", http.StatusBadRequest)
+			return
+		}
+
+		filter, err := parseFilter(r.Form)
+		if err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		query := `
+			insert into saved_feed (name, params) values (?, ?)
+			on conflict (name) do update set params = excluded.params;
+		`
+		if _, err := db.Exec(query, name, filter.Encode()); err != nil {
+			writeError(w, "Error saving feed", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Location", BASEPATH+"/feed/saved/"+name)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, map[string]string{
+			"name":   name,
+			"params": filter.Encode(),
+			"feed":   BASEPATH + "/feed/saved/" + name,
+		})
+	}
+}
+
+// Serve the feed of a saved search over the whole catalog
+func serveSavedFeed(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+
+		var params string
+		err := db.QueryRow("select params from saved_feed where name = ?;", name).Scan(&params)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Unknown saved feed", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error loading saved feed", http.StatusInternalServerError)
+			return
+		}
+
+		q, _ := url.ParseQuery(params)
+		filter, err := parseFilter(q)
+		if err != nil {
+			http.Error(w, "Saved feed has invalid parameters", http.StatusInternalServerError)
+			return
+		}
+
+		ids, err := searchIDs(db, filter, savedFeedLimit)
+		if err != nil {
+			http.Error(w, "Error searching games", http.StatusInternalServerError)
+			return
+		}
+		ids = capItems(w, ids)
+
+		items, err := fetchDataFromDB(r.Context(), db, ids, parseFeedOptions(r))
+		if err != nil {
+			feedError(w, err, "Error generating feed")
+			return
+		}
+
+		feed := newFeed(&Channel{
+			Title:       "F95zone Saved Search: " + name,
+			Link:        "https://f95zone.com/latest",
+			Description: "Games matching " + params,
+		}, items)
+		addHub(feed, topicURL("/feed/saved/"+name))
+		writeFeed(w, r, feed)
+	}
+}
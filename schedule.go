@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// waitJitter delays a scheduled run by a random 0..JITTER so that several
+// instances sharing a schedule don't hit the API at the same moment
+func waitJitter() {
+	if JITTER <= 0 {
+		return
+	}
+
+	delay := rand.N(JITTER)
+	log.Printf("Delaying update by %s, running at %s", delay.Truncate(time.Millisecond), time.Now().Add(delay).Format(time.RFC3339))
+	time.Sleep(delay)
+}
+
+// updatesDisabled reports whether F95_RSS_CRON is empty or off, for
+// instances serving a database another host updates
+func updatesDisabled() bool {
+	return RSSCRON == "" || strings.EqualFold(RSSCRON, "off")
+}
+
+// cronInterval estimates the time between two runs of a cron spec, or
+// returns 0 when the spec can't be parsed
+func cronInterval(spec string) time.Duration {
+	s, err := cron.ParseStandard(spec)
+	if err != nil {
+		return 0
+	}
+
+	next := s.Next(time.Now())
+	return s.Next(next).Sub(next)
+}
+
+// feedTTL returns the configured TTL in minutes, defaulting to the update
+// interval
+func feedTTL() int {
+	if TTL > 0 {
+		return TTL
+	}
+
+	interval := cronInterval(RSSCRON)
+	if interval <= 0 {
+		return 0
+	}
+	return int(math.Ceil(interval.Minutes()))
+}
+
+// logNextRun reports when the scheduled update will next fire
+func logNextRun(c *cron.Cron, id cron.EntryID) {
+	e := c.Entry(id)
+	if !e.Valid() {
+		return
+	}
+
+	if JITTER > 0 {
+		log.Printf("Next update at %s (+ up to %s jitter)", e.Next.Format(time.RFC3339), JITTER)
+	} else {
+		log.Printf("Next update at %s", e.Next.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+// migrations bring databases created by older versions up to date. They run
+// on every start, so each statement must be idempotent.
+var migrations = []string{
+	// Versions are stored normalized, see normalizeVersion
+	`update game set version = trim(version) where version != trim(version);`,
+	`update game set version = substr(version, 2)
+	where lower(substr(version, 1, 1)) = 'v' and substr(version, 2, 1) in ('0', '1', '2', '3', '4', '5', '6', '7', '8', '9');`,
+	`create table if not exists host (
+		id integer primary key autoincrement,
+		base text not null unique
+	);`,
+	`create table if not exists tag (
+		id integer primary key,
+		name text not null
+	);`,
+	`create table if not exists prefix (
+		id integer primary key,
+		name text not null,
+		category text
+	);`,
+	`create table if not exists saved_feed (
+		name text primary key,
+		params text not null,
+		created timestamp default (datetime(current_timestamp, 'localtime'))
+	);`,
+	`create table if not exists update_run (
+		id integer primary key autoincrement,
+		started timestamp not null,
+		duration_ms integer not null,
+		processed integer not null default 0,
+		changed integer not null default 0,
+		error text
+	);`,
+	`create table if not exists pinned (
+		game_id integer primary key,
+		created timestamp default (datetime(current_timestamp, 'localtime'))
+	);`,
+	`create table if not exists status_change (
+		id integer primary key autoincrement,
+		game_id integer not null,
+		status text not null,
+		detected timestamp default (datetime(current_timestamp, 'localtime'))
+	);`,
+	`create table if not exists meta (
+		key text primary key,
+		value text not null
+	);`,
+	`create table if not exists read_state (
+		guid text primary key,
+		created timestamp default (datetime(current_timestamp, 'localtime'))
+	);`,
+	`create table if not exists version_history (
+		id integer primary key autoincrement,
+		game_id integer not null,
+		version text not null,
+		recorded timestamp default (datetime(current_timestamp, 'localtime'))
+	);`,
+	`create index if not exists version_history_game on version_history (game_id);`,
+	`create table if not exists tag_change (
+		id integer primary key autoincrement,
+		game_id integer not null,
+		kind text not null,
+		item_id integer not null,
+		added integer not null,
+		detected timestamp default (datetime(current_timestamp, 'localtime'))
+	);`,
+	`create index if not exists tag_change_game on tag_change (game_id, detected);`,
+	`create table if not exists blocked (
+		game_id integer primary key,
+		created timestamp default (datetime(current_timestamp, 'localtime'))
+	);`,
+	`create table if not exists refetch (
+		game_id integer primary key,
+		queued timestamp default (datetime(current_timestamp, 'localtime'))
+	);`,
+	// Filters, discovery and per-game lookups; the primary keys only cover
+	// lookups by game
+	`create index if not exists game_updated on game (updated);`,
+	`create index if not exists game_creator on game (creator_id);`,
+	`create index if not exists game_rating on game (rating);`,
+	`create index if not exists tags_tag on tags (tag_id, game_id);`,
+	`create index if not exists prefixes_prefix on prefixes (prefix_id, game_id);`,
+	`create index if not exists cover_game on cover (game_id);`,
+	`create index if not exists preview_game on preview (game_id);`,
+	`create table if not exists notified (
+		game_id integer not null,
+		version text not null,
+		channel text not null,
+		sent timestamp default (datetime(current_timestamp, 'localtime')),
+		primary key (game_id, version, channel)
+	);`,
+	`create table if not exists notify_queue (
+		game_id integer primary key,
+		queued timestamp default (datetime(current_timestamp, 'localtime'))
+	);`,
+	// Merged tag ids, mapped to the tag they were merged into
+	`create table if not exists tag_alias (
+		from_id integer primary key,
+		to_id integer not null
+	);`,
+}
+
+func migrateDatabase(db *sql.DB) {
+	for _, m := range migrations {
+		if _, err := db.Exec(sqlDialect.schema(m)); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+
+	columns := []struct{ table, column, def string }{
+		{"game", "rating", "real"},
+		{"game", "overview", "text"},
+		{"update_run", "warnings", "text"},
+		{"creator", "normalized", "text"},
+		{"game", "hash", "text"},
+	}
+	for _, c := range columns {
+		if err := addColumn(db, c.table, c.column, c.def); err != nil {
+			log.Fatalf("Failed to add %s.%s: %v", c.table, c.column, err)
+		}
+	}
+
+	if err := localizeTimestamps(db); err != nil {
+		log.Fatalf("Failed to migrate timestamps: %v", err)
+	}
+
+	if err := migrateURLHosts(db); err != nil {
+		log.Fatalf("Failed to migrate image URLs: %v", err)
+	}
+
+	if err := mergeCreators(db); err != nil {
+		log.Fatalf("Failed to merge duplicate creators: %v", err)
+	}
+}
+
+// hasColumn reports whether table has a column with the given name
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	var n int
+	err := db.QueryRow(sqlDialect.columnQuery(), table, column).Scan(&n)
+	return n > 0, err
+}
+
+// addColumn adds a column to table unless it already exists
+func addColumn(db *sql.DB, table, column, def string) error {
+	ok, err := hasColumn(db, table, column)
+	if err != nil || ok {
+		return err
+	}
+
+	_, err = db.Exec(sqlDialect.schema("alter table " + table + " add column " + column + " " + def + ";"))
+	return err
+}
+
+// migrateURLHosts rewrites cover and preview rows that still store full URLs
+// into host_id + path, keeping their ids
+func migrateURLHosts(db *sql.DB) error {
+	old, err := hasColumn(db, "cover", "url")
+	if err != nil || !old {
+		return err
+	}
+
+	log.Println("Moving cover and preview URLs into the host table...")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"cover", "preview"} {
+		_, err := tx.Exec(`
+			create table ` + table + `_new (
+				id integer primary key autoincrement,
+				host_id integer not null,
+				path text not null,
+				game_id integer,
+				unique(host_id, path),
+				foreign key(host_id) references host(id),
+				foreign key(game_id) references game(id)
+			);
+		`)
+		if err != nil {
+			return err
+		}
+
+		if err := backfillHosts(tx, table); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`drop table ` + table + `; alter table ` + table + `_new rename to ` + table + `;`)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func backfillHosts(tx *sql.Tx, table string) error {
+	type row struct {
+		id     int
+		url    string
+		gameID sql.NullInt64
+	}
+
+	rows, err := tx.Query(`select id, url, game_id from ` + table + `;`)
+	if err != nil {
+		return err
+	}
+
+	var all []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.url, &r.gameID); err != nil {
+			rows.Close()
+			return err
+		}
+		all = append(all, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range all {
+		base, path := splitURL(r.url)
+		hostID, err := upsertHost(tx, base)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`insert into `+table+`_new (id, host_id, path, game_id) values (?, ?, ?, ?);`, r.id, hostID, path, r.gameID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Meta keys of the latest feed snapshot and the one before it
+const (
+	snapshotKey         = "feed_snapshot"
+	previousSnapshotKey = "feed_snapshot_previous"
+)
+
+// FeedSnapshot records what a scheduled run generated: a hash of the whole
+// feed and of each item, keyed by guid
+type FeedSnapshot struct {
+	Hash  string         `json:"hash"`
+	Taken time.Time      `json:"taken"`
+	Items []SnapshotItem `json:"items,omitempty"`
+}
+
+type SnapshotItem struct {
+	GUID  string `json:"guid"`
+	Title string `json:"title"`
+	Hash  string `json:"hash"`
+}
+
+// FeedDiff is what changed between two snapshots
+type FeedDiff struct {
+	Current  *FeedSnapshot  `json:"current"`
+	Previous *FeedSnapshot  `json:"previous"`
+	Added    []SnapshotItem `json:"added"`
+	Removed  []SnapshotItem `json:"removed"`
+	Changed  []SnapshotItem `json:"changed"`
+}
+
+func shortHash(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// snapshotFeed stores a snapshot of feed, moving the last one to
+// previousSnapshotKey
+func snapshotFeed(db *sql.DB, feed *RSS) {
+	s := FeedSnapshot{Taken: time.Now().In(FEEDTZ), Items: []SnapshotItem{}}
+	var hashes []string
+	for _, item := range feed.Channel.Items {
+		si := SnapshotItem{
+			GUID:  itemID(item),
+			Title: item.Title,
+			Hash:  shortHash(item.Title, item.Link, item.Description, item.PubDate.Format(time.RFC3339)),
+		}
+		s.Items = append(s.Items, si)
+		hashes = append(hashes, si.GUID, si.Hash)
+	}
+	s.Hash = shortHash(hashes...)
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		log.Printf("Failed to snapshot the feed: %v", err)
+		return
+	}
+	if last := getMeta(db, snapshotKey); last != "" {
+		setMeta(db, previousSnapshotKey, last)
+	}
+	setMeta(db, snapshotKey, string(data))
+}
+
+// loadSnapshot reads a stored snapshot, nil when there is none
+func loadSnapshot(db *sql.DB, key string) (*FeedSnapshot, error) {
+	v := getMeta(db, key)
+	if v == "" {
+		return nil, nil
+	}
+	var s FeedSnapshot
+	if err := json.Unmarshal([]byte(v), &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// diffSnapshots lists the items of cur that prev lacks or had with other
+// content, and those of prev that cur lacks
+func diffSnapshots(cur, prev *FeedSnapshot) FeedDiff {
+	d := FeedDiff{Current: cur, Previous: prev, Added: []SnapshotItem{}, Removed: []SnapshotItem{}, Changed: []SnapshotItem{}}
+	old := map[string]SnapshotItem{}
+	if prev != nil {
+		for _, item := range prev.Items {
+			old[item.GUID] = item
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, item := range cur.Items {
+		seen[item.GUID] = true
+		before, ok := old[item.GUID]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, item)
+		case before.Hash != item.Hash:
+			d.Changed = append(d.Changed, item)
+		}
+	}
+	if prev != nil {
+		for _, item := range prev.Items {
+			if !seen[item.GUID] {
+				d.Removed = append(d.Removed, item)
+			}
+		}
+	}
+	return d
+}
+
+// serveDiff shows how the feed of the last scheduled run differs from the
+// run before it
+func serveDiff(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cur, err := loadSnapshot(db, snapshotKey)
+		if err != nil {
+			writeError(w, "Error reading snapshot", http.StatusInternalServerError)
+			return
+		}
+		if cur == nil {
+			writeError(w, "No snapshot yet, it is taken after each scheduled update", http.StatusNotFound)
+			return
+		}
+		prev, err := loadSnapshot(db, previousSnapshotKey)
+		if err != nil {
+			writeError(w, "Error reading snapshot", http.StatusInternalServerError)
+			return
+		}
+
+		// The item lists are in the snapshots already
+		d := diffSnapshots(cur, prev)
+		d.Current = &FeedSnapshot{Hash: cur.Hash, Taken: cur.Taken}
+		if prev != nil {
+			d.Previous = &FeedSnapshot{Hash: prev.Hash, Taken: prev.Taken}
+		}
+		writeJSON(w, d)
+	}
+}
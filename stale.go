@@ -0,0 +1,75 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"time"
+)
+
+var STALEAFTER time.Duration // age of the last successful update that puts a warning atop the feed, 0 to disable
+
+// staleItem returns a warning item when no update has succeeded for
+// STALEAFTER, or nil. Its date is the moment the data went stale, so it
+// sorts first and stays the same item for the whole outage.
+func staleItem(db *sql.DB) (*Item, error) {
+	if STALEAFTER <= 0 {
+		return nil, nil
+	}
+
+	var last time.Time
+	succeeded := true
+	err := db.QueryRow("select started from update_run where error is null order by id desc limit 1;").Scan(&last)
+	if err == sql.ErrNoRows {
+		// Count the outage from the first recorded attempt
+		succeeded = false
+		err = db.QueryRow("select started from update_run order by id limit 1;").Scan(&last)
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	age := time.Since(last)
+	if age < STALEAFTER {
+		return nil, nil
+	}
+
+	var lastError string
+	err = db.QueryRow("select coalesce(error, '') from update_run order by id desc limit 1;").Scan(&lastError)
+	if err != nil {
+		return nil, err
+	}
+
+	title := "⚠️ Data is stale — last updated " + formatAge(age) + " ago"
+	description := "<p>The last successful update started at " + last.In(FEEDTZ).Format(time.RFC1123) + ".</p>"
+	if !succeeded {
+		title = "⚠️ Data is stale — no update has succeeded yet"
+		description = "<p>No update has succeeded since " + last.In(FEEDTZ).Format(time.RFC1123) + ".</p>"
+	}
+	if lastError != "" {
+		description += "<p>Latest error: " + html.EscapeString(lastError) + "</p>"
+	}
+
+	return &Item{
+		Title:       title,
+		Link:        "https://f95zone.to/latest",
+		Description: description,
+		GUID:        &GUID{Value: fmt.Sprintf("f95-rss-stale-%d", last.Unix()), IsPermaLink: "false"},
+		PubDate:     RSSDate{last.Add(STALEAFTER).In(FEEDTZ)},
+	}, nil
+}
+
+// formatAge renders a duration in its largest whole unit, e.g. 6h or 3d
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+}
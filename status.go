@@ -0,0 +1,144 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// stalledStatuses are the statuses tracked by the abandoned feed, with the
+// label put in front of item titles
+var stalledStatuses = []struct{ status, label string }{
+	{"abandoned", "Abandoned"},
+	{"onhold", "On Hold"},
+}
+
+// recordStatusChanges notes when a known game gains the abandoned or on
+// hold prefix. It must run before the new prefixes are stored.
+func recordStatusChanges(db *sql.DB, gameID int, prefixes []int) {
+	incoming := make(map[int]bool, len(prefixes))
+	for _, p := range prefixes {
+		incoming[p] = true
+	}
+
+	for _, s := range stalledStatuses {
+		prefix := statusPrefixes[s.status]
+		if !incoming[prefix] {
+			continue
+		}
+
+		_, err := db.Exec(`
+			insert into status_change (game_id, status)
+			select cast(? as integer), ? where exists (select 1 from game where id = ?)
+			and not exists (select 1 from prefixes where game_id = ? and prefix_id = ?);
+		`, gameID, s.status, gameID, gameID, prefix)
+		if err != nil {
+			log.Fatalf("failed to record status change: %v", err)
+		}
+	}
+}
+
+// statusChange is the latest stalled status detected for a game
+type statusChange struct {
+	gameID   int
+	label    string
+	detected time.Time
+}
+
+// fetchStatusChanges returns the latest status change of each game, most
+// recently detected first
+func fetchStatusChanges(db *sql.DB) ([]statusChange, error) {
+	rows, err := db.Query(`
+		select s.game_id, s.status, s.detected from status_change s
+		where s.id = (select max(id) from status_change where game_id = s.game_id)
+		order by s.id desc;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels := map[string]string{}
+	for _, s := range stalledStatuses {
+		labels[s.status] = s.label
+	}
+
+	var changes []statusChange
+	for rows.Next() {
+		var c statusChange
+		var status, detected string
+		if err := rows.Scan(&c.gameID, &status, &detected); err != nil {
+			return nil, err
+		}
+		if c.detected, err = parseDBTime(detected); err != nil {
+			return nil, err
+		}
+		c.label = labels[status]
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// Serve a feed of watched games that were abandoned or put on hold, in the
+// order the change was noticed. ?all=1 covers the whole catalog. Blocked
+// games are left out either way.
+func serveAbandoned(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		changes, err := fetchStatusChanges(db)
+		if err != nil {
+			http.Error(w, "Error reading status changes", http.StatusInternalServerError)
+			return
+		}
+
+		all := r.URL.Query().Get("all") == "1"
+		watched := map[int]bool{}
+		if !all {
+			ids, err := readIDsFromFile(IDFILE)
+			if err != nil {
+				http.Error(w, "Error reading IDs from file", http.StatusInternalServerError)
+				return
+			}
+			for _, id := range ids {
+				watched[id] = true
+			}
+		}
+
+		var ids []int
+		byID := map[int]statusChange{}
+		for _, c := range changes {
+			if !all && !watched[c.gameID] {
+				continue
+			}
+			ids = append(ids, c.gameID)
+			byID[c.gameID] = c
+		}
+		if ids, err = dropBlocked(db, ids); err != nil {
+			http.Error(w, "Error reading blocklist", http.StatusInternalServerError)
+			return
+		}
+		ids = capItems(w, ids)
+
+		items, err := fetchDataFromDB(r.Context(), db, ids, parseFeedOptions(r))
+		if err != nil {
+			feedError(w, err, "Error generating feed")
+			return
+		}
+		for _, item := range items {
+			c, ok := byID[item.GameID]
+			if !ok {
+				continue
+			}
+			item.Title = "[" + c.label + "] " + item.Title
+			item.PubDate = RSSDate{c.detected.In(FEEDTZ)}
+			item.GUID = &GUID{Value: fmt.Sprintf("f95-%d-status-%d", item.GameID, c.detected.Unix()), IsPermaLink: "false"}
+		}
+
+		writeFeed(w, r, newFeed(&Channel{
+			Title:       "F95zone Abandoned Games",
+			Link:        "https://f95zone.com/latest",
+			Description: "Games that were abandoned or put on hold",
+		}, items))
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestServeAbandoned checks an empty watchlist serves no games instead of
+// the whole catalog, and that blocked games are left out in both modes
+func TestServeAbandoned(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	db := newTestDB(t)
+	seedGames(t, db, 3, 1)
+	for _, id := range []int{1, 2, 3} {
+		recordStatusChanges(db, id, []int{statusPrefixes["abandoned"]})
+	}
+	if _, err := db.Exec("insert into blocked (game_id) values (2);"); err != nil {
+		t.Fatal(err)
+	}
+
+	items := func(query string) int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		serveAbandoned(db)(w, httptest.NewRequest(http.MethodGet, "/feed/abandoned"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		return strings.Count(w.Body.String(), "<item>")
+	}
+
+	writeIDFile(t, nil)
+	if got := items(""); got != 0 {
+		t.Errorf("empty watchlist: got %d items, want 0", got)
+	}
+	writeIDFile(t, []int{1, 2})
+	if got := items(""); got != 1 {
+		t.Errorf("watchlist: got %d items, want 1", got)
+	}
+	if got := items("?all=1"); got != 2 {
+		t.Errorf("all=1: got %d items, want 2", got)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// summaryOrder is the order statuses are listed in the summary, 0 being
+// ongoing
+var summaryOrder = []int{0, statusPrefixes["completed"], statusPrefixes["onhold"], statusPrefixes["abandoned"]}
+
+// summaryItem returns an item counting the games of items per status, named
+// after the prefix table where it knows them. It counts the items rather
+// than the requested ids so games the feed filters dropped aren't counted.
+// Its date is the newest of items, so it sorts first and changes along
+// with them.
+func summaryItem(ctx context.Context, db *sql.DB, items []*Item) (*Item, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	args := []any{statusPrefixes["completed"], statusPrefixes["onhold"], statusPrefixes["abandoned"]}
+	for _, item := range items {
+		args = append(args, item.GameID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(items)), ",")
+
+	query := `
+		select s.status, coalesce(n.name, ''), count(*) from (
+			select coalesce((
+				select p.prefix_id from prefixes p
+				where p.game_id = g.id and p.prefix_id in (?, ?, ?)
+				order by p.rowid desc limit 1
+			), 0) as status
+			from game g where g.id in (` + placeholders + `)
+		) s
+		left join prefix n on n.id = s.status
+		group by s.status;
+	`
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[int]int{}
+	names := map[int]string{}
+	for rows.Next() {
+		var status, count int
+		var name string
+		if err := rows.Scan(&status, &name, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+		names[status] = name
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var parts []string
+	for _, status := range summaryOrder {
+		if counts[status] == 0 {
+			continue
+		}
+		name := names[status]
+		if name == "" {
+			name = statusLabels[status]
+		}
+		if name == "" {
+			name = "Ongoing"
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d", name, counts[status]))
+	}
+	summary := strings.Join(parts, ", ")
+
+	latest := lastModified(items)
+	return &Item{
+		Title:       "Summary — " + summary,
+		Link:        "https://f95zone.to/latest",
+		Description: "<p>" + html.EscapeString(summary) + "</p>",
+		GUID:        &GUID{Value: fmt.Sprintf("f95-rss-summary-%d", latest.Unix()), IsPermaLink: "false"},
+		PubDate:     RSSDate{latest},
+	}, nil
+}
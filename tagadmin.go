@@ -0,0 +1,201 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TagMerge reports what a merge of one tag id into another changed
+type TagMerge struct {
+	From       int   `json:"from"`
+	To         int   `json:"to"`
+	Remapped   int64 `json:"remapped"`   // game tags moved to the new id
+	Duplicates int64 `json:"duplicates"` // game tags dropped as the game already had the new id
+	Names      int64 `json:"names"`      // name rows removed
+}
+
+// errMergedTag rejects merging into a tag that was itself merged away
+var errMergedTag = errors.New("tag was merged into another one")
+
+// tagAliases maps merged tag ids to the tag they were merged into
+type tagAliases map[int]int
+
+// loadTagAliases reads the aliases mergeTags recorded
+func loadTagAliases(db *sql.DB) (tagAliases, error) {
+	rows, err := db.Query("select from_id, to_id from tag_alias;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := tagAliases{}
+	for rows.Next() {
+		var from, to int
+		if err := rows.Scan(&from, &to); err != nil {
+			return nil, err
+		}
+		aliases[from] = to
+	}
+	return aliases, rows.Err()
+}
+
+// apply replaces merged tag ids by the tag they were merged into, dropping
+// the duplicates a listing carrying both ends up with
+func (a tagAliases) apply(ids []int) []int {
+	if len(a) == 0 {
+		return ids
+	}
+	seen := make(map[int]bool, len(ids))
+	out := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if to, ok := a[id]; ok {
+			id = to
+		}
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// mergeTags moves every game from tag from to tag to and removes the name
+// of from, keeping it for to when to has none. The merge is recorded as an
+// alias so listings that still carry from are ingested with to. It runs in
+// one transaction under updateMu so an update can't re-add rows halfway.
+func mergeTags(db *sql.DB, from, to int) (TagMerge, error) {
+	m := TagMerge{From: from, To: to}
+
+	updateMu.Lock()
+	defer updateMu.Unlock()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return m, err
+	}
+	defer tx.Rollback()
+
+	var merged bool
+	if err := tx.QueryRow("select exists (select 1 from tag_alias where from_id = ?);", to).Scan(&merged); err != nil {
+		return m, err
+	}
+	if merged {
+		return m, errMergedTag
+	}
+
+	// Earlier merges into from now lead to to
+	if _, err := tx.Exec("update tag_alias set to_id = ? where to_id = ?;", to, from); err != nil {
+		return m, err
+	}
+	_, err = tx.Exec(`
+		insert into tag_alias (from_id, to_id) values (?, ?)
+		on conflict (from_id) do update set to_id = excluded.to_id;
+	`, from, to)
+	if err != nil {
+		return m, err
+	}
+
+	res, err := tx.Exec(`
+		delete from tags
+		where tag_id = ? and game_id in (select game_id from tags where tag_id = ?);
+	`, from, to)
+	if err != nil {
+		return m, err
+	}
+	m.Duplicates, _ = res.RowsAffected()
+
+	res, err = tx.Exec("update tags set tag_id = ? where tag_id = ?;", to, from)
+	if err != nil {
+		return m, err
+	}
+	m.Remapped, _ = res.RowsAffected()
+
+	_, err = tx.Exec(`
+		insert into tag (id, name) select cast(? as integer), name from tag where id = ?
+		on conflict (id) do nothing;
+	`, to, from)
+	if err != nil {
+		return m, err
+	}
+	res, err = tx.Exec("delete from tag where id = ?;", from)
+	if err != nil {
+		return m, err
+	}
+	m.Names, _ = res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return m, err
+	}
+	resetTagCounts()
+	return m, nil
+}
+
+// serveMergeTags merges the tag of the from form value into the one of to
+func serveMergeTags(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, err := strconv.Atoi(r.FormValue("from"))
+		if err != nil || from <= 0 {
+			writeError(w, "Invalid from", http.StatusBadRequest)
+			return
+		}
+		to, err := strconv.Atoi(r.FormValue("to"))
+		if err != nil || to <= 0 {
+			writeError(w, "Invalid to", http.StatusBadRequest)
+			return
+		}
+		if from == to {
+			writeError(w, "from and to are the same tag", http.StatusBadRequest)
+			return
+		}
+
+		m, err := mergeTags(db, from, to)
+		if errors.Is(err, errMergedTag) {
+			writeError(w, fmt.Sprintf("Tag %d was merged into another tag, merge into that one", to), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("Tag merge %d into %d failed: %v", from, to, err)
+			writeError(w, "Error merging tags", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Merged tag %d into %d: %d remapped, %d duplicates", from, to, m.Remapped, m.Duplicates)
+		writeJSON(w, m)
+	}
+}
+
+// serveRenameTag sets the name of the tag of the {id} path value from the
+// name form value, adding the name row if the tag had none
+func serveRenameTag(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil || id <= 0 {
+			writeError(w, "Invalid id", http.StatusBadRequest)
+			return
+		}
+		name := sanitizeText(strings.TrimSpace(r.FormValue("name")))
+		if name == "" {
+			writeError(w, "Missing name", http.StatusBadRequest)
+			return
+		}
+
+		query := `
+			insert into tag (id, name) values (?, ?)
+			on conflict (id) do update set name = excluded.name;
+		`
+		res, err := db.Exec(query, id, name)
+		if err != nil {
+			writeError(w, "Error renaming tag", http.StatusInternalServerError)
+			return
+		}
+		n, _ := res.RowsAffected()
+		resetTagCounts()
+
+		writeJSON(w, map[string]any{"id": id, "name": name, "updated": n})
+	}
+}
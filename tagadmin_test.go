@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"testing"
+)
+
+// TestMergeTagsSurvivesIngest merges a tag and re-ingests a listing that
+// still carries it, which must keep the merged tag without logging a change
+func TestMergeTagsSurvivesIngest(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	db := newTestDB(t)
+	ingest := func() {
+		t.Helper()
+		aliases, err := loadTagAliases(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := F95DATA{ThreadID: 101, Title: "Game", Creator: "Dev", Version: "0.1", Tags: []int{1, 2, 3}}
+		if reason := filterGame(nil, aliases, &f); reason != notSkipped {
+			t.Fatalf("filterGame = %v", reason)
+		}
+		ingestGame(db, f, gameHash(f))
+	}
+	storedTags := func() []int {
+		t.Helper()
+		rows, err := db.Query("select tag_id from tags where game_id = 101 order by tag_id;")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var ids []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+		return ids
+	}
+
+	ingest()
+	if _, err := mergeTags(db, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	var changes int
+	db.QueryRow("select count(*) from tag_change;").Scan(&changes)
+
+	ingest()
+	if got := storedTags(); !slices.Equal(got, []int{2, 3}) {
+		t.Errorf("tags after re-ingest = %v, want [2 3]", got)
+	}
+	var after int
+	db.QueryRow("select count(*) from tag_change;").Scan(&after)
+	if after != changes {
+		t.Errorf("re-ingest logged %d tag changes, want none", after-changes)
+	}
+
+	// A later merge of the target carries the earlier alias along
+	if _, err := mergeTags(db, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+	ingest()
+	if got := storedTags(); !slices.Equal(got, []int{3}) {
+		t.Errorf("tags after second merge = %v, want [3]", got)
+	}
+	if _, err := mergeTags(db, 3, 1); !errors.Is(err, errMergedTag) {
+		t.Errorf("merge into a merged tag: err = %v, want errMergedTag", err)
+	}
+}
+
+func TestTagAliasesApply(t *testing.T) {
+	aliases := tagAliases{1: 2, 4: 2}
+	if got := aliases.apply([]int{1, 2, 3, 4}); !slices.Equal(got, []int{2, 3}) {
+		t.Errorf("apply = %v, want [2 3]", got)
+	}
+	if got := tagAliases(nil).apply([]int{1}); !slices.Equal(got, []int{1}) {
+		t.Errorf("nil apply = %v, want [1]", got)
+	}
+}
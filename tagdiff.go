@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// recordTagChanges stores the tags and prefixes a known game gained or lost
+// since the last update. It must run before the new sets are stored, and
+// after insertGame so the changes aren't older than the version they came
+// with. Each game's changes are written in one statement so they share a
+// timestamp.
+func recordTagChanges(db *sql.DB, gameID int, tags, prefixes []int) {
+	sets := []struct {
+		kind  string
+		query string
+		ids   []int
+	}{
+		{"tag", "select tag_id from tags where game_id = ?;", tags},
+		{"prefix", "select prefix_id from prefixes where game_id = ?;", prefixes},
+	}
+
+	var rows [][]any
+	for _, s := range sets {
+		stored, err := queryIDs(db, s.query, gameID)
+		if err != nil {
+			log.Fatalf("failed to get the %s set of game %d: %v", s.kind, gameID, err)
+		}
+		added, removed := diffIDs(stored, s.ids)
+		for _, id := range added {
+			rows = append(rows, []any{gameID, s.kind, id, 1})
+		}
+		for _, id := range removed {
+			rows = append(rows, []any{gameID, s.kind, id, 0})
+		}
+	}
+
+	err := insertRows(db, `insert into tag_change (game_id, kind, item_id, added) values`, rows)
+	if err != nil {
+		log.Fatalf("failed to record tag changes: %v", err)
+	}
+}
+
+// diffIDs returns the ids of incoming missing from stored, and the ids of
+// stored missing from incoming
+func diffIDs(stored, incoming []int) (added, removed []int) {
+	have := make(map[int]bool, len(stored))
+	for _, id := range stored {
+		have[id] = true
+	}
+	want := make(map[int]bool, len(incoming))
+	for _, id := range incoming {
+		if !want[id] && !have[id] {
+			added = append(added, id)
+		}
+		want[id] = true
+	}
+	for _, id := range stored {
+		if !want[id] {
+			removed = append(removed, id)
+		}
+	}
+	return added, removed
+}
+
+// fetchTagChanges returns the latest changes of a game as "+Name" and
+// "-Name", falling back to the id for names the metadata doesn't know.
+// Changes older than the current version are left out, they belong to an
+// earlier update.
+func fetchTagChanges(ctx context.Context, db *sql.DB, gameID int) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		select c.kind, c.item_id, c.added, coalesce(t.name, p.name, '') from tag_change c
+		left join tag t on c.kind = 'tag' and t.id = c.item_id
+		left join prefix p on c.kind = 'prefix' and p.id = c.item_id
+		where c.game_id = ? and c.detected = (select max(detected) from tag_change where game_id = ?)
+			and c.detected >= coalesce((select max(recorded) from version_history where game_id = ?), '')
+		order by c.added desc, c.id;
+	`, gameID, gameID, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []string
+	for rows.Next() {
+		var kind, name string
+		var id int
+		var added bool
+		if err := rows.Scan(&kind, &id, &added, &name); err != nil {
+			return nil, err
+		}
+		if name == "" {
+			name = kind + " " + strconv.Itoa(id)
+		}
+		sign := "-"
+		if added {
+			sign = "+"
+		}
+		changes = append(changes, sign+name)
+	}
+	return changes, rows.Err()
+}
+
+// deleteMissing removes the rows of a game's set whose id is not in ids
+func deleteMissing(db *sql.DB, table, column string, gameID int, ids []int) error {
+	query := "delete from " + table + " where game_id = ?"
+	args := []any{gameID}
+	if len(ids) > 0 {
+		query += " and " + column + " not in (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	_, err := db.Exec(query+";", args...)
+	return err
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"testing"
+)
+
+// TestFetchTagChangesCurrentVersion checks tag changes are shown with the
+// version they came with and dropped once a newer version arrives
+func TestFetchTagChangesCurrentVersion(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	db := newTestDB(t)
+	ingest := func(version string, tags ...int) {
+		f := F95DATA{ThreadID: 101, Title: "Game", Creator: "Dev", Version: version, Tags: tags}
+		ingestGame(db, f, gameHash(f))
+	}
+	changes := func() []string {
+		t.Helper()
+		c, err := fetchTagChanges(context.Background(), db, 101)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	ingest("0.1", 1, 2)
+	if got := changes(); len(got) != 0 {
+		t.Errorf("new game: changes = %v, want none", got)
+	}
+
+	ingest("0.2", 1, 3)
+	if got := changes(); !slices.Equal(got, []string{"+tag 3", "-tag 2"}) {
+		t.Errorf("after 0.2: changes = %v, want [+tag 3 -tag 2]", got)
+	}
+
+	// Move the 0.2 update into the past, timestamps only have seconds
+	for _, q := range []string{
+		"update tag_change set detected = '2024-01-01 00:00:00';",
+		"update version_history set recorded = '2024-01-01 00:00:00';",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ingest("0.3", 1, 3)
+	if got := changes(); len(got) != 0 {
+		t.Errorf("after 0.3: changes = %v, want none", got)
+	}
+}
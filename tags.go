@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// TagCount is a tag with the number of games carrying it
+type TagCount struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name,omitempty"`
+	Games int    `json:"games"`
+}
+
+// tagCounts caches the tag list; it only changes with updates and
+// metadata refreshes, which clear it
+var tagCounts struct {
+	sync.Mutex
+	tags []TagCount
+}
+
+func resetTagCounts() {
+	tagCounts.Lock()
+	tagCounts.tags = nil
+	tagCounts.Unlock()
+}
+
+// fetchTagCounts returns every tag in use, most used first
+func fetchTagCounts(db *sql.DB) ([]TagCount, error) {
+	tagCounts.Lock()
+	defer tagCounts.Unlock()
+	if tagCounts.tags != nil {
+		return tagCounts.tags, nil
+	}
+
+	query := `
+		select t.tag_id, coalesce(n.name, ''), count(*) as games
+		from tags t
+		left join tag n on n.id = t.tag_id
+		group by t.tag_id
+		order by games desc, t.tag_id;
+	`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []TagCount{}
+	for rows.Next() {
+		var t TagCount
+		if err := rows.Scan(&t.ID, &t.Name, &t.Games); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tagCounts.tags = tags
+	return tags, nil
+}
+
+// serveTags lists the tags with their game counts. ?min=N drops tags on
+// fewer than N games.
+func serveTags(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		minGames := 0
+		if v := r.URL.Query().Get("min"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeError(w, "Invalid min", http.StatusBadRequest)
+				return
+			}
+			minGames = n
+		}
+
+		tags, err := fetchTagCounts(db)
+		if err != nil {
+			writeError(w, "Error listing tags", http.StatusInternalServerError)
+			return
+		}
+
+		out := []TagCount{}
+		for _, t := range tags {
+			if t.Games >= minGames {
+				out = append(out, t)
+			}
+		}
+		writeJSON(w, out)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+var COVERTHUMBS bool // show cover thumbnails in descriptions, linking to the full cover
+
+// coverThumb derives the thumbnail of an f95zone attachment, which is kept
+// in a thumb directory next to the full image. It returns "" for covers
+// hosted elsewhere. The thumbnail is derived when rendering rather than
+// stored, so turning F95_RSS_COVER_THUMBS on or off needs no re-ingest.
+func coverThumb(cover string) string {
+	u, err := url.Parse(cover)
+	if err != nil || !strings.HasPrefix(u.Host, "attachments.") || !allowedImageHost(u) {
+		return ""
+	}
+
+	dir, file := path.Split(u.Path)
+	if file == "" || path.Base(dir) == "thumb" {
+		return ""
+	}
+	u.Path = dir + "thumb/" + file
+	return u.String()
+}
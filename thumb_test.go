@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCoverThumb(t *testing.T) {
+	tests := []struct {
+		name, cover, want string
+	}{
+		{"attachment", "https://attachments.f95zone.to/2024/01/123_cover.jpg", "https://attachments.f95zone.to/2024/01/thumb/123_cover.jpg"},
+		{"query kept", "https://attachments.f95zone.to/2024/01/1.jpg?v=2", "https://attachments.f95zone.to/2024/01/thumb/1.jpg?v=2"},
+		{"already a thumbnail", "https://attachments.f95zone.to/2024/01/thumb/1.jpg", ""},
+		{"other f95zone host", "https://preview.f95zone.to/2024/01/1.jpg", ""},
+		{"attachments host off the allowlist", "https://attachments.example.com/2024/01/1.jpg", ""},
+		{"other host", "https://i.imgur.com/abc.jpg", ""},
+		{"directory", "https://attachments.f95zone.to/2024/01/", ""},
+		{"empty", "", ""},
+		{"not a URL", "://", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := coverThumb(tt.cover); got != tt.want {
+				t.Errorf("coverThumb(%q) = %q, want %q", tt.cover, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribeThumb(t *testing.T) {
+	const cover = "https://i.imgur.com/abc.jpg"
+	const full = `<img src="https://i.imgur.com/abc.jpg" alt="Game" />`
+
+	// Covers without a thumbnail fall back to the full image
+	item := &Item{Name: "Game", Link: "https://f95zone.to/threads/1", Cover: cover, Thumb: coverThumb(cover)}
+	if d := describe(item); !strings.Contains(d, full) || strings.Contains(d, "<a href=\""+cover) {
+		t.Errorf("description %s, want the plain cover", d)
+	}
+
+	item.Cover = "https://attachments.f95zone.to/2024/01/1.jpg"
+	item.Thumb = coverThumb(item.Cover)
+	want := `<a href="https://attachments.f95zone.to/2024/01/1.jpg"><img src="https://attachments.f95zone.to/2024/01/thumb/1.jpg" alt="Game" /></a>`
+	if d := describe(item); !strings.Contains(d, want) {
+		t.Errorf("description %s, want the thumbnail linking to the cover", d)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+)
+
+// defaultTitleTemplate renders "Title [version]", dropping the brackets
+// when there is no version
+const defaultTitleTemplate = `{{.Title}}{{with .Version}} [{{.}}]{{end}}`
+
+// TITLETEMPLATE formats item titles, e.g.
+// {{with .Status}}[{{.}}] {{end}}{{.Title}}{{with .Version}} — v{{.}}{{end}}{{with .Creator}} ({{.}}){{end}}
+var TITLETEMPLATE string
+
+// titleTemplate is the parsed TITLETEMPLATE, set by validateConfig
+var titleTemplate = template.Must(parseTitleTemplate(defaultTitleTemplate))
+
+// statusLabels names the status prefixes in titles
+var statusLabels = map[int]string{
+	statusPrefixes["completed"]: "Completed",
+	statusPrefixes["onhold"]:    "On Hold",
+	statusPrefixes["abandoned"]: "Abandoned",
+}
+
+// TitleFields are the values available to the title template. Status is
+// empty for ongoing games.
+type TitleFields struct {
+	Title   string
+	Version string
+	Creator string
+	Status  string
+}
+
+// parseTitleTemplate parses a title template, rejecting fields that
+// TitleFields doesn't have when it runs
+func parseTitleTemplate(text string) (*template.Template, error) {
+	t, err := template.New("title").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid F95_RSS_TITLE_TEMPLATE: %w", err)
+	}
+	return t, nil
+}
+
+// itemTitle renders the title template. The result is plain text, escaped
+// when the feed is marshalled, with runs of whitespace collapsed so empty
+// fields leave no gaps. Templates wrap optional fields in {{with}} to drop
+// their brackets; brackets in the values themselves are kept. A failing
+// template falls back to the bare title.
+func itemTitle(f TitleFields) string {
+	var b strings.Builder
+	if err := titleTemplate.Execute(&b, f); err != nil {
+		log.Printf("Title template failed for %q: %v", f.Title, err)
+		return f.Title
+	}
+
+	title := strings.Join(strings.Fields(b.String()), " ")
+	if title == "" {
+		return f.Title
+	}
+	return title
+}
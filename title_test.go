@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"text/template"
+)
+
+func TestItemTitle(t *testing.T) {
+	const custom = `{{with .Status}}[{{.}}] {{end}}{{.Title}}{{with .Version}} — v{{.}}{{end}}{{with .Creator}} ({{.}}){{end}}`
+
+	tests := []struct {
+		name     string
+		template string
+		fields   TitleFields
+		want     string
+	}{
+		{"default", defaultTitleTemplate, TitleFields{Title: "Game", Version: "0.1"}, "Game [0.1]"},
+		{"default without version", defaultTitleTemplate, TitleFields{Title: "Game"}, "Game"},
+		{"brackets in the title are kept", defaultTitleTemplate, TitleFields{Title: "Game []"}, "Game []"},
+		{"brackets in the version are kept", defaultTitleTemplate, TitleFields{Title: "Game", Version: "(beta)"}, "Game [(beta)]"},
+		{"custom", custom, TitleFields{Title: "Game", Version: "1.0", Creator: "Dev", Status: "Completed"}, "[Completed] Game — v1.0 (Dev)"},
+		{"custom without optional fields", custom, TitleFields{Title: "Game"}, "Game"},
+		{"whitespace collapsed", `{{.Title}}   {{.Version}}   {{.Creator}}`, TitleFields{Title: "Game", Creator: "Dev"}, "Game Dev"},
+		{"empty render falls back", `{{.Version}}`, TitleFields{Title: "Game"}, "Game"},
+	}
+
+	defer func(t *template.Template) { titleTemplate = t }(titleTemplate)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			titleTemplate = template.Must(parseTitleTemplate(tt.template))
+			if got := itemTitle(tt.fields); got != tt.want {
+				t.Errorf("itemTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTitleTemplateInvalid(t *testing.T) {
+	if _, err := parseTitleTemplate("{{.Title"); err == nil {
+		t.Error("unclosed action parsed without an error")
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parsedFeed is the subset of RSS 2.0 checked by validateFeed, read back
+// from the marshalled XML rather than from the structs that produced it
+type parsedFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel *struct {
+		Title       string       `xml:"title"`
+		Links       []parsedLink `xml:"link"` // includes atom:link
+		Description string       `xml:"description"`
+		TTL         string       `xml:"ttl"`
+		Items       []struct {
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			Description string `xml:"description"`
+			PubDate     string `xml:"pubDate"`
+			GUID        *struct {
+				Value       string `xml:",chardata"`
+				IsPermaLink string `xml:"isPermaLink,attr"`
+			} `xml:"guid"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type parsedLink struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// rssLink returns the channel's own <link>, ignoring namespaced ones
+func rssLink(links []parsedLink) string {
+	for _, l := range links {
+		if l.XMLName.Space == "" {
+			return l.Value
+		}
+	}
+	return ""
+}
+
+// validateFeed runs basic RSS 2.0 checks on a marshalled feed and returns
+// the problems found
+func validateFeed(data []byte) []string {
+	var feed parsedFeed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return []string{"not well-formed XML: " + err.Error()}
+	}
+
+	problems := []string{}
+	if feed.Version != "2.0" {
+		problems = append(problems, fmt.Sprintf("rss version is %q, want 2.0", feed.Version))
+	}
+
+	c := feed.Channel
+	if c == nil {
+		return append(problems, "missing channel")
+	}
+	if c.Title == "" {
+		problems = append(problems, "channel: missing title")
+	}
+	if c.Description == "" {
+		problems = append(problems, "channel: missing description")
+	}
+	if link := rssLink(c.Links); !absoluteURL(link) {
+		problems = append(problems, fmt.Sprintf("channel: link %q is not an absolute URL", link))
+	}
+	if c.TTL != "" {
+		if n, err := strconv.Atoi(c.TTL); err != nil || n < 0 {
+			problems = append(problems, fmt.Sprintf("channel: invalid ttl %q", c.TTL))
+		}
+	}
+
+	guids := map[string]int{}
+	for i, item := range c.Items {
+		where := fmt.Sprintf("item %d", i+1)
+		if item.Title != "" {
+			where += fmt.Sprintf(" (%s)", item.Title)
+		}
+
+		if item.Title == "" && item.Description == "" {
+			problems = append(problems, where+": needs a title or a description")
+		}
+		if item.Link != "" && !absoluteURL(item.Link) {
+			problems = append(problems, fmt.Sprintf("%s: link %q is not an absolute URL", where, item.Link))
+		}
+		if item.PubDate != "" {
+			if _, err := time.Parse(time.RFC1123Z, item.PubDate); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: pubDate %q is not RFC 822", where, item.PubDate))
+			}
+		}
+
+		if g := item.GUID; g != nil {
+			value := strings.TrimSpace(g.Value)
+			switch {
+			case value == "":
+				problems = append(problems, where+": empty guid")
+			case g.IsPermaLink != "false" && !absoluteURL(value):
+				problems = append(problems, fmt.Sprintf("%s: permalink guid %q is not a URL", where, value))
+			}
+			if first, ok := guids[value]; ok && value != "" {
+				problems = append(problems, fmt.Sprintf("%s: guid %q repeats item %d", where, value, first))
+			} else {
+				guids[value] = i + 1
+			}
+		}
+	}
+
+	return problems
+}
+
+func absoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// serveValidate builds the feed as /feed would and reports RSS problems.
+// Feed options such as ?inline=1 or ?compact=1 are honoured.
+func serveValidate(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ids, err := readIDsFromFile(IDFILE)
+		if err != nil {
+			writeError(w, "Error reading IDs from file", http.StatusInternalServerError)
+			return
+		}
+
+		feed, err := generateFeed(r.Context(), db, ids, parseFeedOptions(r))
+		if err != nil {
+			writeError(w, "Error generating feed", http.StatusInternalServerError)
+			return
+		}
+		addSelfLink(feed, requestURL(r))
+
+		data, err := xml.Marshal(feed)
+		if err != nil {
+			writeError(w, "Error converting feed to XML", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, map[string]any{
+			"items":    len(feed.Channel.Items),
+			"problems": validateFeed(data),
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var ALLOWDOWNGRADE bool // store versions that sort below the stored one
+
+// normalizeVersion trims and collapses whitespace and drops a leading "v"
+// before a digit, so "  v0.8.2 " and "0.8.2" are stored alike
+func normalizeVersion(v string) string {
+	v = strings.Join(strings.Fields(v), " ")
+	if len(v) > 1 && (v[0] == 'v' || v[0] == 'V') && unicode.IsDigit(rune(v[1])) {
+		v = v[1:]
+	}
+	return v
+}
+
+// versionChunks splits a version into runs of digits and runs of letters,
+// dropping separators: "v0.8.2b" -> ["0", "8", "2", "b"]
+func versionChunks(v string) []string {
+	v = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(v)), "v")
+
+	var chunks []string
+	start := -1
+	digits := false
+	for i, r := range v {
+		isDigit := unicode.IsDigit(r)
+		isLetter := unicode.IsLetter(r)
+		if start >= 0 && (!(isDigit || isLetter) || isDigit != digits) {
+			chunks = append(chunks, v[start:i])
+			start = -1
+		}
+		if start < 0 && (isDigit || isLetter) {
+			start, digits = i, isDigit
+		}
+	}
+	if start >= 0 {
+		chunks = append(chunks, v[start:])
+	}
+	return chunks
+}
+
+// compareVersions leniently orders two version strings, returning -1, 0 or 1.
+// Numeric chunks compare as numbers and letters compare alphabetically, so
+// "1.10" > "1.9" and "1.1b" > "1.1a". Versions without any digits can't be
+// ordered and compare equal.
+func compareVersions(a, b string) int {
+	ca, cb := versionChunks(a), versionChunks(b)
+	if !hasDigits(ca) || !hasDigits(cb) {
+		return 0
+	}
+
+	for i := 0; i < len(ca) && i < len(cb); i++ {
+		if c := compareChunk(ca[i], cb[i]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(ca) < len(cb):
+		return -1
+	case len(ca) > len(cb):
+		return 1
+	}
+	return 0
+}
+
+func compareChunk(a, b string) int {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		if na < nb {
+			return -1
+		} else if na > nb {
+			return 1
+		}
+		return 0
+	case errA == nil:
+		// A number outranks a letter suffix at the same position: 1.1 > 1.a
+		return 1
+	case errB == nil:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+func hasDigits(chunks []string) bool {
+	for _, c := range chunks {
+		if c != "" && unicode.IsDigit(rune(c[0])) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"0.9", "1.0", -1},
+		{"1.10", "1.9", 1},
+		{"v0.8.2", "0.8.2", 0},
+		{"0.8.2", "0.8.10", -1},
+		{"1.1b", "1.1a", 1},
+		{"1.1", "1.1a", -1}, // a letter suffix is a later patch
+		{"1.0", "1.0.1", -1},
+		{"Ch. 3", "Ch. 2", 1},
+		{"Episode 10", "Episode 9", 1},
+		{"0.5 Beta", "0.5 Alpha", 1},
+		{"Final", "1.0", 0},
+		{"", "1.0", 0},
+		{"Final", "Demo", 0},
+		{"0.12.1", "0.12.1b", -1},
+		{"1.1", "1.a", 1},
+		{"2024-06-01", "2024-05-30", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			if got := compareVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := compareVersions(tt.b, tt.a); got != -tt.want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeVersion(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"0.8.2", "0.8.2"},
+		{"  v0.8.2 ", "0.8.2"},
+		{"V1.0", "1.0"},
+		{"v", "v"},
+		{"vFinal", "vFinal"},
+		{"Ch.  3   Part 2", "Ch. 3 Part 2"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeVersion(tt.in); got != tt.want {
+			t.Errorf("normalizeVersion(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
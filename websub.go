@@ -0,0 +1,131 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var hubClient = &http.Client{Timeout: 15 * time.Second}
+
+// feedURL returns the public topic URL of the feed
+func feedURL() string {
+	return topicURL("/feed")
+}
+
+// topicURL returns the public URL of a feed path, which is its WebSub topic
+func topicURL(path string) string {
+	return strings.TrimRight(PUBLICURL, "/") + BASEPATH + path
+}
+
+// addHub advertises the hub on a feed, with topic as its self link so
+// readers subscribe to the URL that gets pinged
+func addHub(feed *RSS, topic string) {
+	if WEBSUBHUB == "" || PUBLICURL == "" {
+		return
+	}
+	feed.AtomNS = "http://www.w3.org/2005/Atom"
+	feed.Channel.AtomLinks = append(feed.Channel.AtomLinks,
+		&AtomLink{Href: topic, Rel: "self", Type: "application/rss+xml"},
+		&AtomLink{Href: WEBSUBHUB, Rel: "hub"},
+	)
+}
+
+// hubTopics returns the topics an update may have changed: the feed in
+// each format when a game of ids changed, and /feed/all and the saved
+// feeds when any game did
+func hubTopics(db *sql.DB, ids, changed []int) []string {
+	if len(changed) == 0 {
+		return nil
+	}
+
+	var topics []string
+	if containsAny(ids, changed) {
+		topics = append(topics, feedURL(), topicURL("/feed.atom"), topicURL("/feed.json"))
+	}
+	topics = append(topics, topicURL("/feed/all"))
+
+	rows, err := db.Query("select name from saved_feed order by name;")
+	if err != nil {
+		log.Printf("Failed to list saved feeds for WebSub: %v", err)
+		return topics
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			log.Printf("Failed to list saved feeds for WebSub: %v", err)
+			break
+		}
+		topics = append(topics, topicURL("/feed/saved/"+name))
+	}
+	return topics
+}
+
+// requestURL returns the absolute URL a feed was requested at
+func requestURL(r *http.Request) string {
+	if PUBLICURL != "" {
+		return strings.TrimRight(PUBLICURL, "/") + BASEPATH + r.URL.RequestURI()
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + BASEPATH + r.URL.RequestURI()
+}
+
+// addSelfLink advertises href as the feed's own URL unless one is set
+func addSelfLink(feed *RSS, href string) {
+	for _, link := range feed.Channel.AtomLinks {
+		if link.Rel == "self" {
+			return
+		}
+	}
+	feed.AtomNS = "http://www.w3.org/2005/Atom"
+	feed.Channel.AtomLinks = append([]*AtomLink{{Href: href, Rel: "self", Type: "application/rss+xml"}}, feed.Channel.AtomLinks...)
+}
+
+// pingHub notifies the configured WebSub hub that topic has new content.
+// Publishing is best-effort: failures are logged and otherwise ignored.
+func pingHub(topic string) {
+	if WEBSUBHUB == "" || PUBLICURL == "" {
+		return
+	}
+
+	resp, err := hubClient.PostForm(WEBSUBHUB, url.Values{
+		"hub.mode": {"publish"},
+		"hub.url":  {topic},
+	})
+	if err != nil {
+		log.Printf("WebSub ping to %s failed: %v", WEBSUBHUB, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		log.Printf("WebSub hub %s rejected ping for %s: %s", WEBSUBHUB, topic, resp.Status)
+		return
+	}
+	log.Printf("WebSub hub notified for %s", topic)
+}
+
+// containsAny reports whether any of needles is present in haystack
+func containsAny(haystack, needles []int) bool {
+	set := make(map[int]bool, len(haystack))
+	for _, id := range haystack {
+		set[id] = true
+	}
+	for _, id := range needles {
+		if set[id] {
+			return true
+		}
+	}
+	return false
+}
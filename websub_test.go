@@ -0,0 +1,34 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestHubTopics checks every feed advertising the hub is pinged when its
+// games may have changed
+func TestHubTopics(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.Exec("insert into saved_feed (name, params) values ('rpg', 'tag=1');"); err != nil {
+		t.Fatal(err)
+	}
+	oldURL, oldBase := PUBLICURL, BASEPATH
+	PUBLICURL, BASEPATH = "https://rss.example.com/", "/rss"
+	defer func() { PUBLICURL, BASEPATH = oldURL, oldBase }()
+
+	base := "https://rss.example.com/rss"
+	tests := []struct {
+		name         string
+		ids, changed []int
+		want         []string
+	}{
+		{"no change", []int{1}, nil, nil},
+		{"watched", []int{1}, []int{1}, []string{base + "/feed", base + "/feed.atom", base + "/feed.json", base + "/feed/all", base + "/feed/saved/rpg"}},
+		{"unwatched", []int{1}, []int{2}, []string{base + "/feed/all", base + "/feed/saved/rpg"}},
+	}
+	for _, tt := range tests {
+		if got := hubTopics(db, tt.ids, tt.changed); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: topics = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}